@@ -7,6 +7,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/helm"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
@@ -109,6 +110,13 @@ func (o *BuildOptions) Run() error {
 			App:     app,
 		}
 	}
+	if app, ok := o.Config.Helm[o.AppName]; ok {
+		app.Name = o.AppName
+		m = &helm.Manager{
+			Project: o.Config,
+			App:     app,
+		}
+	}
 	if app, ok := o.Config.Alias[o.AppName]; ok {
 		app.Name = o.AppName
 		m = &alias.Manager{
@@ -151,6 +151,9 @@ func (o *DeployOptions) Run() error {
 
 	if app, ok := o.Config.Helm[o.AppName]; o.Config.AppsProvider == "helm" || ok {
 		providerUsed = "helm"
+		if !ok {
+			app = &config.Helm{}
+		}
 		app.Name = o.AppName
 		app.Force = o.Force
 		m = &helm.Manager{
@@ -10,13 +10,12 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/helm"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/internal/requirements"
-	"github.com/hazelops/ize/internal/terraform"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/pterm/pterm"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -25,8 +24,10 @@ type DownOptions struct {
 	AppName          string
 	SkipBuildAndPush bool
 	AutoApprove      bool
+	AllowDestroy     bool
 	SkipGen          bool
 	UseYarn          bool
+	Parallelism      int
 	ui               terminal.UI
 }
 
@@ -42,6 +43,9 @@ var downExample = templates.Examples(`
 	# Destroy app (config file required)
 	ize down <app name>
 
+	# Destroy all, taking down up to 4 independent apps at a time
+	ize down --auto-approve --parallelism 4
+
 	# Destroy app with explicitly specified config file
 	ize --config-file (or -c) /path/to/config down <app name>
 
@@ -95,6 +99,8 @@ func NewCmdDown(project *config.Project) *cobra.Command {
 	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "approve deploy all")
 	cmd.Flags().BoolVar(&o.UseYarn, "use-yarn", false, "execute commands using yarn")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().BoolVar(&o.AllowDestroy, "allow-destroy", false, "destroy terraform stacks despite their destroy policy")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 1, "number of apps and terraform stacks to destroy at the same time (0 means no limit)")
 
 	cmd.AddCommand(
 		NewCmdDownInfra(project),
@@ -113,19 +119,7 @@ func (o *DownOptions) Complete(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("you must specify at least one terraform stack in ize.toml")
 		}
 
-		if _, ok := o.Config.Terraform["infra"]; ok {
-			if len(o.Config.Terraform["infra"].AwsProfile) == 0 {
-				o.Config.Terraform["infra"].AwsProfile = o.Config.AwsProfile
-			}
-
-			if len(o.Config.Terraform["infra"].AwsRegion) == 0 {
-				o.Config.Terraform["infra"].AwsRegion = o.Config.AwsRegion
-			}
-
-			if len(o.Config.Terraform["infra"].Version) == 0 {
-				o.Config.Terraform["infra"].Version = o.Config.TerraformVersion
-			}
-		}
+		setTerraformDefaults(o.Config)
 	} else {
 		if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
 			return err
@@ -209,98 +203,86 @@ func (o *DownOptions) validateAll() error {
 		return fmt.Errorf("can't validate options: namespace must be specified")
 	}
 
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism can't be negative")
+	}
+
 	return nil
 }
 
 func destroyAll(ui terminal.UI, o *DownOptions) error {
+	ui.Output("Destroying apps and infra...", terminal.WithHeaderStyle())
 
-	ui.Output("Destroying apps...", terminal.WithHeaderStyle())
-	sg := ui.StepGroup()
-	defer sg.Wait()
+	// apps are destroyed with the profile of the infra stack
+	appsConfig := *o.Config
+	if v, ok := o.Config.Terraform["infra"]; ok {
+		appsConfig.AwsProfile = v.AwsProfile
+	}
 
-	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
+	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetAll(), func(c context.Context, name string) error {
+		if _, ok := o.Config.Terraform[name]; ok {
+			return destroyInfra(name, o.Config, o.SkipGen, o.AutoApprove, o.AllowDestroy, ui)
+		}
 
-		return destroyApp(name, o.Config, o.AutoApprove, ui)
-	})
+		return destroyApp(name, &appsConfig, o.AutoApprove, ui)
+	}, manager.WithParallelism(o.Parallelism))
 	if err != nil {
 		return err
 	}
 
-	if _, ok := o.Config.Terraform["infra"]; ok {
-		err = destroyInfra("infra", o.Config, o.SkipGen, ui)
-		if err != nil {
-			return err
-		}
-	}
-
-	err = manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
-
-		return destroyInfra(name, o.Config, o.SkipGen, ui)
-	})
-
 	ui.Output("Destroy all completed!\n", terminal.WithSuccessStyle())
 	time.Sleep(time.Millisecond * 200)
 
 	return nil
 }
 
-func destroyInfra(state string, config *config.Project, skipGen bool, ui terminal.UI) error {
-	if !skipGen {
-		err := GenerateTerraformFiles(state, "", config)
-		if err != nil {
-			return err
-		}
+func destroyInfra(state string, config *config.Project, skipGen bool, autoApprove bool, allowDestroy bool, ui terminal.UI) error {
+	tf, err := newTerraform(state, config, skipGen)
+	if err != nil {
+		return fmt.Errorf("can't destroy infra: %w", err)
 	}
 
-	var tf terraform.Terraform
-
-	logrus.Infof("infra: %s", tf)
+	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
 
-	v, err := config.Session.Config.Credentials.Get()
+	err = tf.RunUI(ui)
 	if err != nil {
-		return fmt.Errorf("can't set AWS credentials: %w", err)
+		return err
 	}
 
-	env := []string{
-		fmt.Sprintf("ENV=%v", config.Env),
-		fmt.Sprintf("AWS_PROFILE=%v", config.Terraform["infra"].AwsProfile),
-		fmt.Sprintf("TF_LOG=%v", config.TFLog),
-		fmt.Sprintf("TF_LOG_PATH=%v", config.TFLogPath),
-		fmt.Sprintf("AWS_ACCESS_KEY_ID=%v", v.AccessKeyID),
-		fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%v", v.SecretAccessKey),
-		fmt.Sprintf("AWS_SESSION_TOKEN=%v", v.SessionToken),
+	err = selectWorkspace(ui, tf, config, state)
+	if err != nil {
+		return fmt.Errorf("can't destroy infra: %w", err)
 	}
 
-	switch config.PreferRuntime {
-	case "docker":
-		tf = terraform.NewDockerTerraform(state, []string{"init", "-input=true"}, env, nil, config)
-	case "native":
-		tf = terraform.NewLocalTerraform(state, []string{"init", "-input=true"}, env, nil, config)
-		err = tf.Prepare()
-		if err != nil {
-			return fmt.Errorf("can't destroy infra: %w", err)
-		}
-	default:
-		return fmt.Errorf("can't supported %s runtime", config.PreferRuntime)
-	}
+	outPath := planPath(config, state)
 
-	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
+	//terraform destroy plan run options
+	tf.NewCmd([]string{"plan", "-destroy", fmt.Sprintf("-out=%s", outPath)})
+
+	ui.Output("Execution terraform plan -destroy...", terminal.WithHeaderStyle())
 
 	err = tf.RunUI(ui)
 	if err != nil {
-		return err
+		return fmt.Errorf("can't destroy infra: %w", err)
+	}
+
+	apply, err := reviewPlan(ui, tf, config, state, outPath, autoApprove, allowDestroy)
+	if err != nil {
+		return fmt.Errorf("can't destroy infra: %w", err)
+	}
+
+	if !apply {
+		return nil
 	}
 
 	//terraform destroy run options
-	tf.NewCmd([]string{"destroy", "-auto-approve"})
+	tf.NewCmd([]string{"apply", "-auto-approve", outPath})
 
 	ui.Output("Execution terraform destroy...", terminal.WithHeaderStyle())
 
 	err = tf.RunUI(ui)
 	if err != nil {
-		return fmt.Errorf("can't deploy infra: %w", err)
+		return fmt.Errorf("can't destroy infra: %w", err)
 	}
 
 	ui.Output("Terraform destroy completed!\n", terminal.WithSuccessStyle())
@@ -312,6 +294,11 @@ func destroyApp(name string, cfg *config.Project, autoApprove bool, ui terminal.
 	var m manager.Manager
 	var icon string
 
+	// like in deployApp, every app gets a copy of the project for its own
+	// AWS clients
+	project := *cfg
+	cfg = &project
+
 	m = &ecs.Manager{
 		Project: cfg,
 		App:     &config.Ecs{Name: name},
@@ -325,6 +312,14 @@ func destroyApp(name string, cfg *config.Project, autoApprove bool, ui terminal.
 		}
 		icon = app.Icon
 	}
+	if app, ok := cfg.Helm[name]; ok {
+		app.Name = name
+		m = &helm.Manager{
+			Project: cfg,
+			App:     app,
+		}
+		icon = app.Icon
+	}
 	if app, ok := cfg.Alias[name]; ok {
 		app.Name = name
 		m = &alias.Manager{
@@ -346,14 +341,21 @@ func destroyApp(name string, cfg *config.Project, autoApprove bool, ui terminal.
 		icon += " "
 	}
 
-	ui.Output("Destroying %s%s app...\n", icon, name, terminal.WithHeaderStyle())
+	// every app gets its own step group, so apps destroyed in parallel
+	// don't mix up their progress
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	s := sg.Add("Destroying %s%s app...", icon, name)
+	defer func() { s.Abort() }()
 
 	err := m.Destroy(ui, autoApprove)
 	if err != nil {
 		return fmt.Errorf("can't down: %w", err)
 	}
 
-	ui.Output("Destroy app %s%s completed\n", icon, name, terminal.WithSuccessStyle())
+	s.Update("Destroy app %s%s completed", icon, name)
+	s.Done()
 
 	return nil
 }
@@ -7,18 +7,21 @@ import (
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/internal/terraform"
 	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/spf13/cobra"
 )
 
 type DownInfraOptions struct {
-	Config     *config.Project
-	ui         terminal.UI
-	Version    string
-	AwsProfile string
-	AwsRegion  string
-	SkipGen    bool
-	OnlyInfra  bool
+	Config       *config.Project
+	ui           terminal.UI
+	Version      string
+	AwsProfile   string
+	AwsRegion    string
+	SkipGen      bool
+	OnlyInfra    bool
+	AutoApprove  bool
+	AllowDestroy bool
 }
 
 func NewDownInfraFlags(project *config.Project) *DownInfraOptions {
@@ -58,7 +61,9 @@ func NewCmdDownInfra(project *config.Project) *cobra.Command {
 	cmd.Flags().StringVar(&o.AwsProfile, "infra.terraform.aws-profile", "", "set aws profile")
 	cmd.Flags().StringVar(&o.AwsRegion, "infra.terraform.aws-region", "", "set aws region")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().BoolVar(&o.AllowDestroy, "allow-destroy", false, "destroy terraform stacks despite their destroy policy")
 	cmd.Flags().BoolVar(&o.OnlyInfra, "only-infra", false, "down only infra state")
+	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "destroy without confirmation")
 
 	return cmd
 }
@@ -94,7 +99,7 @@ func (o *DownInfraOptions) Complete() error {
 		}
 
 		if len(o.Config.Terraform["infra"].Version) == 0 {
-			o.Config.Terraform["infra"].Version = o.Config.TerraformVersion
+			o.Config.Terraform["infra"].Version = terraform.DefaultVersion(o.Config, "infra")
 		}
 	}
 
@@ -115,14 +120,14 @@ func (o *DownInfraOptions) Run() error {
 	ui := o.ui
 
 	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := destroyInfra("infra", o.Config, o.SkipGen, ui)
+		err := destroyInfra("infra", o.Config, o.SkipGen, o.AutoApprove, o.AllowDestroy, ui)
 		if err != nil {
 			return err
 		}
 	}
 
 	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		return destroyInfra(name, o.Config, o.SkipGen, ui)
+		return destroyInfra(name, o.Config, o.SkipGen, o.AutoApprove, o.AllowDestroy, ui)
 	})
 	if err != nil {
 
@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/fatih/color"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/internal/terraform"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+type DriftOptions struct {
+	Config      *config.Project
+	SkipGen     bool
+	Parallelism int
+	JSONReport  string
+	ui          terminal.UI
+}
+
+// driftReport is the JSON report of ize drift.
+type driftReport struct {
+	Env       string       `json:"env"`
+	Namespace string       `json:"namespace"`
+	Drifted   bool         `json:"drifted"`
+	Stacks    []stackDrift `json:"stacks"`
+}
+
+type stackDrift struct {
+	Stack     string   `json:"stack"`
+	Drifted   bool     `json:"drifted"`
+	Resources []string `json:"resources,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+var driftLongDesc = templates.LongDesc(`
+	Detect infrastructure drift of the infra stack and all terraform stacks.
+	Every stack is checked with terraform plan -refresh-only -detailed-exitcode in dependency order,
+	the result is printed as a table and, with --json-report, saved as a JSON report.
+	The command exits with a non-zero code when a drift is found or a stack can't be checked.
+`)
+
+var driftExample = templates.Examples(`
+	# Detect drift of all terraform stacks
+	ize drift
+
+	# Check up to 4 independent stacks at a time and save the JSON report
+	ize drift --parallelism 4 --json-report drift.json
+
+	# Print only the JSON report to stdout
+	ize drift --json-report - | jq .stacks
+`)
+
+func NewDriftFlags(project *config.Project) *DriftOptions {
+	return &DriftOptions{
+		Config: project,
+	}
+}
+
+func NewCmdDrift(project *config.Project) *cobra.Command {
+	o := NewDriftFlags(project)
+
+	cmd := &cobra.Command{
+		Use:     "drift",
+		Example: driftExample,
+		Short:   "Detect infrastructure drift",
+		Long:    driftLongDesc,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete()
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 1, "number of terraform stacks to check at the same time (0 means no limit)")
+	cmd.Flags().StringVar(&o.JSONReport, "json-report", "", "save the drift report as JSON to the file (- for stdout, the other output goes to stderr then)")
+
+	return cmd
+}
+
+func (o *DriftOptions) Complete() error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	if o.Config.Terraform == nil {
+		return fmt.Errorf("you must specify at least one terraform stack in ize.toml")
+	}
+
+	setTerraformDefaults(o.Config)
+
+	o.ui = driftUI(o.JSONReport, o.Config.PlainText)
+
+	return nil
+}
+
+// driftUI returns the UI of ize drift. When the JSON report is written to
+// stdout, the UI and the output of terraform are written to stderr, so that
+// stdout contains only the report.
+func driftUI(jsonReport string, plain bool) terminal.UI {
+	if jsonReport != "-" {
+		return terminal.ConsoleUI(context.Background(), plain)
+	}
+
+	color.Output = os.Stderr
+	pterm.SetDefaultOutput(os.Stderr)
+
+	return terminal.NonInteractiveUI(context.Background())
+}
+
+func (o *DriftOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("can't validate options: env must be specified")
+	}
+
+	if len(o.Config.Namespace) == 0 {
+		return fmt.Errorf("can't validate options: namespace must be specified")
+	}
+
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism can't be negative")
+	}
+
+	return nil
+}
+
+func (o *DriftOptions) Run() error {
+	ui := o.ui
+
+	ui.Output("Detecting drift of terraform stacks...", terminal.WithHeaderStyle())
+
+	var mu sync.Mutex
+	report := driftReport{
+		Env:       o.Config.Env,
+		Namespace: o.Config.Namespace,
+	}
+
+	// a stack that can't be checked is reported, the other stacks are still checked
+	check := func(name string) {
+		d := detectDrift(name, ui, o.Config, o.SkipGen)
+
+		mu.Lock()
+		defer mu.Unlock()
+		report.Stacks = append(report.Stacks, d)
+	}
+
+	if _, ok := o.Config.Terraform["infra"]; ok {
+		check("infra")
+	}
+
+	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
+		check(name)
+		return nil
+	}, manager.WithParallelism(o.Parallelism))
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(report.Stacks, func(i, j int) bool {
+		return report.Stacks[i].Stack < report.Stacks[j].Stack
+	})
+
+	var drifted, failed []string
+	for _, d := range report.Stacks {
+		switch {
+		case len(d.Error) != 0:
+			failed = append(failed, d.Stack)
+		case d.Drifted:
+			drifted = append(drifted, d.Stack)
+		}
+	}
+	report.Drifted = len(drifted) != 0
+
+	ui.Table(driftTable(report.Stacks))
+
+	if len(o.JSONReport) != 0 {
+		err = writeDriftReport(o.JSONReport, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(failed) != 0:
+		return fmt.Errorf("can't detect drift of %s", strings.Join(failed, ", "))
+	case len(drifted) != 0:
+		return fmt.Errorf("drift detected in %s", strings.Join(drifted, ", "))
+	}
+
+	ui.Output("No drift detected\n", terminal.WithSuccessStyle())
+
+	return nil
+}
+
+// detectDrift runs a refresh-only plan of the stack. Terraform exits with
+// code 2 when the plan has changes, i.e. the infrastructure has drifted.
+func detectDrift(name string, ui terminal.UI, config *config.Project, skipGen bool) stackDrift {
+	d := stackDrift{Stack: name}
+
+	resources, drifted, err := refreshOnlyPlan(name, ui, config, skipGen)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+
+	d.Drifted = drifted
+	d.Resources = resources
+
+	return d
+}
+
+func refreshOnlyPlan(name string, ui terminal.UI, config *config.Project, skipGen bool) ([]string, bool, error) {
+	tf, err := newTerraform(name, config, skipGen)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return nil, false, fmt.Errorf("can't init: %w", err)
+	}
+
+	err = selectWorkspace(ui, tf, config, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	outPath := filepath.Join(filepath.Dir(planPath(config, name)), "drift.tfplan")
+
+	tf.NewCmd([]string{"plan", "-refresh-only", "-detailed-exitcode", "-input=false", fmt.Sprintf("-out=%s", outPath)})
+
+	err = tf.RunUI(ui)
+	if err == nil {
+		return nil, false, nil
+	}
+
+	var exitErr *terraform.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		return nil, false, fmt.Errorf("can't plan: %w", err)
+	}
+
+	var out bytes.Buffer
+
+	tf.NewCmd([]string{"show", "-json", outPath})
+	tf.SetOut(&out)
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return nil, true, fmt.Errorf("can't show plan: %w", err)
+	}
+
+	plan, err := terraform.ParsePlan(out.Bytes())
+	if err != nil {
+		return nil, true, err
+	}
+
+	return plan.DriftedResources(), true, nil
+}
+
+func driftTable(stacks []stackDrift) *terminal.Table {
+	t := terminal.NewTable("Stack", "Status", "Drifted resources")
+
+	for _, d := range stacks {
+		switch {
+		case len(d.Error) != 0:
+			t.Rich([]string{d.Stack, "error", d.Error}, []string{"", terminal.Red})
+		case d.Drifted:
+			t.Rich([]string{d.Stack, "drifted", strconv.Itoa(len(d.Resources))}, []string{"", terminal.Yellow})
+		default:
+			t.Rich([]string{d.Stack, "no drift", "0"}, []string{"", terminal.Green})
+		}
+	}
+
+	return t
+}
+
+func writeDriftReport(path string, report driftReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal drift report: %w", err)
+	}
+
+	b = append(b, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+
+	err = os.WriteFile(path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("can't write drift report: %w", err)
+	}
+
+	return nil
+}
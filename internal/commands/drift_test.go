@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/pterm/pterm"
+)
+
+func Test_writeDriftReport(t *testing.T) {
+	report := driftReport{
+		Env:       "dev",
+		Namespace: "testnut",
+		Drifted:   true,
+		Stacks: []stackDrift{
+			{Stack: "infra", Drifted: true, Resources: []string{"aws_security_group.db"}},
+			{Stack: "vpc"},
+			{Stack: "dns", Error: "can't init: exit status: 1"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "drift.json")
+
+	if err := writeDriftReport(path, report); err != nil {
+		t.Fatalf("writeDriftReport() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got driftReport
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("writeDriftReport() wrote invalid JSON: %v\n%s", err, b)
+	}
+
+	if !reflect.DeepEqual(got, report) {
+		t.Errorf("writeDriftReport() = %+v, want %+v", got, report)
+	}
+
+	rows := driftTable(report.Stacks).Rows
+	for i, want := range []string{"drifted", "no drift", "error"} {
+		if rows[i][1].Value != want {
+			t.Errorf("driftTable() status of %s = %s, want %s", report.Stacks[i].Stack, rows[i][1].Value, want)
+		}
+	}
+}
+
+func Test_driftUI_stdoutReport(t *testing.T) {
+	output, stderr := color.Output, os.Stderr
+	defer func() {
+		color.Output, os.Stderr = output, stderr
+		pterm.SetDefaultOutput(os.Stdout)
+	}()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	os.Stderr = f
+
+	ui := driftUI("-", false)
+	ui.Output("Detecting drift of terraform stacks...", terminal.WithHeaderStyle())
+	ui.Table(driftTable([]stackDrift{{Stack: "vpc"}}))
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Detecting drift", "vpc", "no drift"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("stderr = %q, want it to contain %q", b, want)
+		}
+	}
+}
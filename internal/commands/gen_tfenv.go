@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
+	gotemplate "text/template"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -14,6 +18,8 @@ import (
 	"github.com/pterm/pterm"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 type TfenvOptions struct {
@@ -23,6 +29,16 @@ type TfenvOptions struct {
 
 var tfenvLongDesc = templates.LongDesc(`
 	tfenv generates backend.tf and variable.tfvars files.
+
+	Variables of a stack are merged from the following sources, each one
+	overriding the previous ones:
+
+	1. variables generated by ize (env, namespace, region, ...)
+	2. var_files, in the order they are listed
+	3. inputs, outputs of other stacks
+	4. vars
+
+	Names of variables in vars and inputs keep their case.
 `)
 
 var tfenvExample = templates.Examples(`
@@ -81,65 +97,22 @@ func GenerateTerraformFiles(name string, terraformStateBucketName string, projec
 		tf = *project.Terraform[name]
 	}
 
-	if len(terraformStateBucketName) != 0 {
-		tf.StateBucketName = terraformStateBucketName
-	}
-
-	if len(tf.StateBucketName) == 0 {
-		legacyBucketExists := checkTFStateBucket(project, fmt.Sprintf("%s-tf-state", project.Namespace))
-		// If we found an existing bucket that conforms with the legacy format use it.
-		if legacyBucketExists {
-			tf.StateBucketName = fmt.Sprintf("%s-tf-state", project.Namespace)
-		} else {
-			resp, err := project.AWSClient.STSClient.GetCallerIdentity(
-				&sts.GetCallerIdentityInput{},
-			)
-			if err != nil {
-				return err
-			}
-
-			// If we haven't found an existing legacy format state bucket use a <NAMESPACE>-<AWS_ACCOUNT>-tf-state bucket as default (unless overridden with other parameters).
-			tf.StateBucketName = fmt.Sprintf("%s-%s-tf-state", project.Namespace, *resp.Account)
-		}
-	}
-
-	stateKey := fmt.Sprintf("%v/%v.tfstate", project.Env, name)
-	if len(tf.StateName) != 0 {
-		stateKey = fmt.Sprintf("%v/%v.tfstate", project.Env, tf.StateName)
-	} else if name == "infra" {
-		stateKey = filepath.Join(project.Env, "terraform.tfstate")
-	}
-
-	if len(tf.StateBucketRegion) == 0 {
-		tf.StateBucketRegion = project.AwsRegion
-	}
-
-	backendOpts := template.BackendOpts{
-		ENV:                            project.Env,
-		LOCALSTACK_ENDPOINT:            project.EndpointUrl,
-		TERRAFORM_STATE_BUCKET_NAME:    tf.StateBucketName,
-		TERRAFORM_STATE_KEY:            stateKey,
-		TERRAFORM_STATE_REGION:         tf.StateBucketRegion,
-		TERRAFORM_STATE_PROFILE:        project.AwsProfile,
-		TERRAFORM_STATE_DYNAMODB_TABLE: "tf-state-lock",
-		TERRAFORM_AWS_PROVIDER_VERSION: "",
-		NAMESPACE:                      project.Namespace,
+	backendOpts, err := backendOptions(name, terraformStateBucketName, tf, project)
+	if err != nil {
+		return err
 	}
 
-	stackPath := filepath.Join(project.EnvDir, name)
-	if name == "infra" {
-		stackPath = project.EnvDir
-	}
+	stackPath := project.StackDir(name)
 
 	if len(tf.TerraformConfigFile) == 0 {
 		tf.TerraformConfigFile = "backend.tf"
 	}
 
-	logrus.Debugf("backend opts: %s", backendOpts)
+	logrus.Debugf("backend opts: %v", backendOpts)
 	logrus.Debugf("state dir path: %s", stackPath)
 	logrus.Debugf("config file name: %s", tf.TerraformConfigFile)
 
-	err := template.GenerateBackendTf(
+	err = template.GenerateBackendTf(
 		backendOpts,
 		filepath.Join(stackPath, tf.TerraformConfigFile),
 	)
@@ -163,7 +136,12 @@ func GenerateTerraformFiles(name string, terraformStateBucketName string, projec
 		varsOpts.DOCKER_REGISTRY = project.DockerRegistry
 	}
 
-	logrus.Debugf("backend opts: %s", varsOpts)
+	varsOpts.VARS, err = stackVars(tf, project)
+	if err != nil {
+		return fmt.Errorf("can't generate tfvars of %s: %w", name, err)
+	}
+
+	logrus.Debugf("vars opts: %v", varsOpts)
 	logrus.Debugf("state dir path: %s", stackPath)
 
 	err = template.GenerateVarsTf(
@@ -180,6 +158,285 @@ func GenerateTerraformFiles(name string, terraformStateBucketName string, projec
 	return nil
 }
 
+// backendOptions returns the options of the state backend of the stack. The
+// S3 state bucket is looked up only for the s3 backend, so the other backends
+// don't need AWS access.
+func backendOptions(name string, terraformStateBucketName string, tf config.Terraform, project *config.Project) (template.BackendOpts, error) {
+	backend := tf.Backend
+	if len(backend.Type) == 0 {
+		backend.Type = "s3"
+	}
+
+	opts := template.BackendOpts{
+		ENV:                 project.Env,
+		LOCALSTACK_ENDPOINT: project.EndpointUrl,
+		NAMESPACE:           project.Namespace,
+		BACKEND_TYPE:        backend.Type,
+	}
+
+	switch backend.Type {
+	case "s3":
+		if len(backend.Bucket) != 0 {
+			tf.StateBucketName = backend.Bucket
+		}
+
+		if len(terraformStateBucketName) != 0 {
+			tf.StateBucketName = terraformStateBucketName
+		}
+
+		if len(tf.StateBucketName) == 0 {
+			legacyBucketExists := checkTFStateBucket(project, fmt.Sprintf("%s-tf-state", project.Namespace))
+			// If we found an existing bucket that conforms with the legacy format use it.
+			if legacyBucketExists {
+				tf.StateBucketName = fmt.Sprintf("%s-tf-state", project.Namespace)
+			} else {
+				resp, err := project.AWSClient.STSClient.GetCallerIdentity(
+					&sts.GetCallerIdentityInput{},
+				)
+				if err != nil {
+					return opts, err
+				}
+
+				// If we haven't found an existing legacy format state bucket use a <NAMESPACE>-<AWS_ACCOUNT>-tf-state bucket as default (unless overridden with other parameters).
+				tf.StateBucketName = fmt.Sprintf("%s-%s-tf-state", project.Namespace, *resp.Account)
+			}
+		}
+
+		stateKey := fmt.Sprintf("%v/%v.tfstate", project.Env, name)
+		if len(backend.Key) != 0 {
+			stateKey = backend.Key
+		} else if tf.IsWorkspaceLayout() {
+			// the env is the workspace, so the states of all envs are kept
+			// under <workspace_key_prefix>/<env>/<name>.tfstate. The key
+			// names the stack, because the default workspace is stored at
+			// the key itself and would be shared by all stacks otherwise.
+			stateKey = fmt.Sprintf("%v.tfstate", name)
+			if len(tf.StateName) != 0 {
+				stateKey = fmt.Sprintf("%v.tfstate", tf.StateName)
+			}
+		} else if len(tf.StateName) != 0 {
+			stateKey = fmt.Sprintf("%v/%v.tfstate", project.Env, tf.StateName)
+		} else if name == "infra" {
+			stateKey = filepath.Join(project.Env, "terraform.tfstate")
+		}
+
+		if len(backend.WorkspaceKeyPrefix) == 0 && tf.IsWorkspaceLayout() {
+			backend.WorkspaceKeyPrefix = name
+			if len(tf.StateName) != 0 {
+				backend.WorkspaceKeyPrefix = tf.StateName
+			}
+		}
+
+		if len(backend.Region) != 0 {
+			tf.StateBucketRegion = backend.Region
+		}
+
+		if len(tf.StateBucketRegion) == 0 {
+			tf.StateBucketRegion = project.AwsRegion
+		}
+
+		if len(backend.Profile) == 0 {
+			backend.Profile = project.AwsProfile
+		}
+
+		// the lock file replaces the DynamoDB table unless the table is set explicitly
+		if len(backend.DynamoDBTable) == 0 && !backend.UseLockfile {
+			backend.DynamoDBTable = "tf-state-lock"
+		}
+
+		opts.TERRAFORM_STATE_BUCKET_NAME = tf.StateBucketName
+		opts.TERRAFORM_STATE_KEY = stateKey
+		opts.TERRAFORM_STATE_REGION = tf.StateBucketRegion
+		opts.TERRAFORM_STATE_PROFILE = backend.Profile
+		opts.TERRAFORM_STATE_DYNAMODB_TABLE = backend.DynamoDBTable
+		opts.TERRAFORM_STATE_USE_LOCKFILE = backend.UseLockfile
+		opts.TERRAFORM_STATE_WORKSPACE_KEY_PREFIX = backend.WorkspaceKeyPrefix
+	case "local":
+		opts.LOCAL_PATH = backend.Path
+	case "http":
+		if tf.IsWorkspaceLayout() {
+			return opts, fmt.Errorf("can't generate backend of %s: the http backend doesn't support workspaces", name)
+		}
+
+		if len(backend.Address) == 0 {
+			return opts, fmt.Errorf("can't generate backend of %s: address of the http backend must be specified", name)
+		}
+
+		opts.HTTP_ADDRESS = backend.Address
+		opts.HTTP_LOCK_ADDRESS = backend.LockAddress
+		opts.HTTP_UNLOCK_ADDRESS = backend.UnlockAddress
+		opts.HTTP_LOCK_METHOD = backend.LockMethod
+		opts.HTTP_UNLOCK_METHOD = backend.UnlockMethod
+		opts.HTTP_USERNAME = backend.Username
+		opts.HTTP_SKIP_CERT_VERIFICATION = backend.SkipCertVerification
+	case "pg":
+		if len(backend.SchemaName) == 0 {
+			// every stack needs its own schema, the default workspace of a schema holds a single state
+			backend.SchemaName = strings.ReplaceAll(fmt.Sprintf("%s_%s", project.Env, name), "-", "_")
+			if tf.IsWorkspaceLayout() {
+				backend.SchemaName = strings.ReplaceAll(name, "-", "_")
+			}
+		}
+
+		opts.PG_CONN_STR = backend.ConnStr
+		opts.PG_SCHEMA_NAME = backend.SchemaName
+	default:
+		return opts, fmt.Errorf("can't generate backend of %s: %s backend isn't supported (s3, local, http or pg)", name, backend.Type)
+	}
+
+	return opts, nil
+}
+
+// stackVars returns the variables of the stack set in ize.toml: var_files are
+// merged in order, inputs override them and vars override inputs. Paths and
+// string values are templates executed with the project, e.g. {{.Env}}, and
+// the output function returning an output of another stack, e.g.
+// {{output "infra" "vpc_id"}}.
+func stackVars(tf config.Terraform, project *config.Project) (map[string]cty.Value, error) {
+	vars := map[string]cty.Value{}
+
+	if len(tf.VarFiles) == 0 && len(tf.Inputs) == 0 && len(tf.Vars) == 0 {
+		return vars, nil
+	}
+
+	outputs := map[string]map[string]stackOutput{}
+	output := func(stack string, name string) (interface{}, error) {
+		if _, ok := outputs[stack]; !ok {
+			o, err := getTerraformOutputs(project.AWSClient.SSMClient, project.Env, stack)
+			if err != nil {
+				return nil, err
+			}
+			outputs[stack] = o
+		}
+
+		o, ok := outputs[stack][name]
+		if !ok {
+			return nil, fmt.Errorf("output %s of %s doesn't exist", name, stack)
+		}
+
+		return o.Value, nil
+	}
+	funcs := gotemplate.FuncMap{"output": output}
+
+	for _, f := range tf.VarFiles {
+		path, err := executeVarTemplate(f, project, funcs)
+		if err != nil {
+			return nil, err
+		}
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(project.RootDir, path)
+		}
+
+		fileVars, err := template.ReadVarFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+
+	for k, input := range tf.Inputs {
+		stack, name, ok := strings.Cut(input, ".")
+		if !ok {
+			return nil, fmt.Errorf("input %s must be <stack>.<output>, got %s", k, input)
+		}
+
+		v, err := output(stack, name)
+		if err != nil {
+			return nil, fmt.Errorf("can't resolve input %s: %w", k, err)
+		}
+
+		vars[k], err = toCtyValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert %s: %w", k, err)
+		}
+	}
+
+	for k, v := range tf.Vars {
+		v, err := executeVarTemplates(v, project, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("can't execute template of %s: %w", k, err)
+		}
+
+		vars[k], err = toCtyValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert %s: %w", k, err)
+		}
+	}
+
+	return vars, nil
+}
+
+// executeVarTemplates executes the string values of the variable, including
+// the values nested in lists and maps.
+func executeVarTemplates(v interface{}, project *config.Project, funcs gotemplate.FuncMap) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return executeVarTemplate(val, project, funcs)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i := range val {
+			item, err := executeVarTemplates(val[i], project, funcs)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k := range val {
+			item, err := executeVarTemplates(val[k], project, funcs)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = item
+		}
+		return m, nil
+	}
+
+	return v, nil
+}
+
+func executeVarTemplate(text string, project *config.Project, funcs gotemplate.FuncMap) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	t, err := gotemplate.New("var").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = t.Execute(&buf, project)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// toCtyValue converts a value decoded from ize.toml to the terraform type
+// system through JSON.
+func toCtyValue(v interface{}) (cty.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	t, err := ctyjson.ImpliedType(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	return ctyjson.Unmarshal(b, t)
+}
+
 func checkTFStateBucket(project *config.Project, name string) bool {
 	_, err := project.AWSClient.S3Client.HeadBucket(&s3.HeadBucketInput{
 		Bucket: aws.String(name),
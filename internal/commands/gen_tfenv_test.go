@@ -2,16 +2,20 @@ package commands
 
 import (
 	_ "embed"
+	"encoding/base64"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/golang/mock/gomock"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/template"
 	"github.com/hazelops/ize/pkg/mocks"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/zclconf/go-cty/cty"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -433,3 +437,128 @@ namespace         = "testnut"
 		})
 	}
 }
+
+func Test_stackVars(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "common.tfvars"), []byte("instance_type = \"t3.micro\"\ninstance_count = 1\nzones = [\"a\", \"b\"]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "dev.tfvars.json"), []byte(`{"instance_count": 2}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSSMAPI := mocks.NewMockSSMAPI(ctrl)
+	mockSSMAPI.EXPECT().GetParameter(gomock.Any()).Return(&ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{
+			Value: aws.String(base64.StdEncoding.EncodeToString([]byte(`{"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-0123"}, "private_subnets": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-a", "subnet-b"]}}`))),
+		},
+	}, nil).Times(2)
+
+	project := &config.Project{
+		Env:       "dev",
+		Namespace: "testnut",
+		RootDir:   dir,
+		AWSClient: config.NewAWSClient(config.WithSSMClient(mockSSMAPI)),
+	}
+
+	tf := config.Terraform{
+		VarFiles: []string{"common.tfvars", "{{.Env}}.tfvars.json"},
+		Inputs: map[string]string{
+			"subnets":       "infra.private_subnets",
+			"instance_type": "infra.vpc_id",
+		},
+		Vars: map[string]interface{}{
+			"instance_type": "t3.small",
+			"domain":        "{{.Env}}.{{.Namespace}}.com",
+			"vpc_id":        `{{output "infra" "vpc_id"}}`,
+			"tags":          map[string]interface{}{"vpc": `{{output "infra" "vpc_id"}}`},
+		},
+	}
+
+	got, err := stackVars(tf, project)
+	if err != nil {
+		t.Fatalf("stackVars() error = %v", err)
+	}
+
+	want := map[string]cty.Value{
+		"instance_type":  cty.StringVal("t3.small"),
+		"instance_count": cty.NumberIntVal(2),
+		"zones":          cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		"domain":         cty.StringVal("dev.testnut.com"),
+		"vpc_id":         cty.StringVal("vpc-0123"),
+		"tags":           cty.ObjectVal(map[string]cty.Value{"vpc": cty.StringVal("vpc-0123")}),
+		"subnets":        cty.TupleVal([]cty.Value{cty.StringVal("subnet-a"), cty.StringVal("subnet-b")}),
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("stackVars() = %#v, want %#v", got, want)
+	}
+	for k, v := range want {
+		if !got[k].RawEquals(v) {
+			t.Errorf("stackVars()[%s] = %#v, want %#v", k, got[k], v)
+		}
+	}
+
+	tf.Vars = map[string]interface{}{"subnet": `{{output "infra" "subnet_id"}}`}
+	tf.VarFiles = nil
+	tf.Inputs = nil
+
+	if _, err = stackVars(tf, project); err == nil {
+		t.Errorf("stackVars() with missing output error = nil, want error")
+	}
+}
+
+func Test_backendOptions_workspaceLayout(t *testing.T) {
+	project := &config.Project{Env: "dev", Namespace: "testnut", AwsRegion: "us-east-1", AwsProfile: "default"}
+
+	tests := []struct {
+		name    string
+		tf      config.Terraform
+		want    template.BackendOpts
+		wantErr bool
+	}{
+		{
+			name: "s3",
+			tf:   config.Terraform{Layout: config.LayoutWorkspace, StateBucketName: "testnut-tf-state"},
+			want: template.BackendOpts{
+				ENV:                                  "dev",
+				NAMESPACE:                            "testnut",
+				BACKEND_TYPE:                         "s3",
+				TERRAFORM_STATE_BUCKET_NAME:          "testnut-tf-state",
+				TERRAFORM_STATE_KEY:                  "vpc.tfstate",
+				TERRAFORM_STATE_REGION:               "us-east-1",
+				TERRAFORM_STATE_PROFILE:              "default",
+				TERRAFORM_STATE_DYNAMODB_TABLE:       "tf-state-lock",
+				TERRAFORM_STATE_WORKSPACE_KEY_PREFIX: "vpc",
+			},
+		},
+		{
+			name: "pg",
+			tf:   config.Terraform{Layout: config.LayoutWorkspace, Backend: config.Backend{Type: "pg"}},
+			want: template.BackendOpts{ENV: "dev", NAMESPACE: "testnut", BACKEND_TYPE: "pg", PG_SCHEMA_NAME: "vpc"},
+		},
+		{
+			name:    "http",
+			tf:      config.Terraform{Layout: config.LayoutWorkspace, Backend: config.Backend{Type: "http", Address: "https://state.example.com"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backendOptions("vpc", "", tt.tf, project)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("backendOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backendOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
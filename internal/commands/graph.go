@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+type GraphOptions struct {
+	Config *config.Project
+	Format string
+	out    io.Writer
+}
+
+var graphLongDesc = templates.LongDesc(`
+	Print the dependency graph of terraform stacks and apps.
+	Every node is followed by the nodes it depends on.
+	Supported formats are tree (default), dot and mermaid.
+`)
+
+var graphExample = templates.Examples(`
+	# Print the graph as a text tree
+	ize graph
+
+	# Render the graph with Graphviz
+	ize graph --format dot | dot -Tsvg > graph.svg
+
+	# Print the graph as a Mermaid flowchart
+	ize graph --format mermaid
+`)
+
+func NewGraphFlags(project *config.Project) *GraphOptions {
+	return &GraphOptions{
+		Config: project,
+	}
+}
+
+func NewCmdGraph(project *config.Project) *cobra.Command {
+	o := NewGraphFlags(project)
+
+	cmd := &cobra.Command{
+		Use:     "graph",
+		Short:   "Print the dependency graph of terraform stacks and apps",
+		Long:    graphLongDesc,
+		Example: graphExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Format, "format", "tree", "set output format (tree, dot or mermaid)")
+
+	return cmd
+}
+
+func (o *GraphOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	o.out = cmd.OutOrStdout()
+
+	return nil
+}
+
+func (o *GraphOptions) Validate() error {
+	switch o.Format {
+	case "tree", "dot", "mermaid":
+	default:
+		return fmt.Errorf("can't validate options: unsupported format %s (supported: tree, dot, mermaid)", o.Format)
+	}
+
+	return nil
+}
+
+func (o *GraphOptions) Run() error {
+	g := manager.NewGraph(o.Config.GetAll(), manager.AppStopped)
+
+	var out string
+	switch o.Format {
+	case "dot":
+		out = graphDOT(g, o.Config)
+	case "mermaid":
+		out = graphMermaid(g, o.Config)
+	default:
+		out = graphTree(g, o.Config)
+	}
+
+	_, err := fmt.Fprint(o.out, out)
+	if err != nil {
+		return fmt.Errorf("can't print graph: %w", err)
+	}
+
+	return nil
+}
+
+// graphTree prints every node nothing depends on, followed by the tree of its
+// dependencies. Shared dependencies are printed under each of their dependents.
+func graphTree(g *manager.Graph, project *config.Project) string {
+	var b strings.Builder
+
+	var walk func(v *manager.Vertex, prefix string, last bool, root bool)
+	walk = func(v *manager.Vertex, prefix string, last bool, root bool) {
+		childPrefix := prefix
+		switch {
+		case root:
+			fmt.Fprintf(&b, "%s (%s)\n", v.Key, project.GetKind(v.Key))
+		case last:
+			fmt.Fprintf(&b, "%s└── %s (%s)\n", prefix, v.Key, project.GetKind(v.Key))
+			childPrefix += "    "
+		default:
+			fmt.Fprintf(&b, "%s├── %s (%s)\n", prefix, v.Key, project.GetKind(v.Key))
+			childPrefix += "│   "
+		}
+
+		children := sortedVertices(v.Children)
+		for i, c := range children {
+			walk(c, childPrefix, i == len(children)-1, false)
+		}
+	}
+
+	for _, v := range sortedVertices(g.Vertices) {
+		if len(v.Parents) == 0 {
+			walk(v, "", true, true)
+		}
+	}
+
+	return b.String()
+}
+
+func graphDOT(g *manager.Graph, project *config.Project) string {
+	var b strings.Builder
+
+	b.WriteString("digraph ize {\n")
+	b.WriteString("  rankdir=LR;\n")
+
+	vertices := sortedVertices(g.Vertices)
+	for _, v := range vertices {
+		shape := "box"
+		if project.GetKind(v.Key) == "terraform" {
+			shape = "cylinder"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s];\n", v.Key, fmt.Sprintf("%s (%s)", v.Key, project.GetKind(v.Key)), shape)
+	}
+
+	for _, v := range vertices {
+		for _, c := range sortedVertices(v.Children) {
+			fmt.Fprintf(&b, "  %q -> %q;\n", v.Key, c.Key)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+var mermaidIDReplacer = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func graphMermaid(g *manager.Graph, project *config.Project) string {
+	var b strings.Builder
+
+	id := func(key string) string {
+		return mermaidIDReplacer.ReplaceAllString(key, "_")
+	}
+
+	b.WriteString("graph TD\n")
+
+	vertices := sortedVertices(g.Vertices)
+	for _, v := range vertices {
+		label := fmt.Sprintf("%s (%s)", v.Key, project.GetKind(v.Key))
+		if project.GetKind(v.Key) == "terraform" {
+			fmt.Fprintf(&b, "  %s[(\"%s\")]\n", id(v.Key), label)
+		} else {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id(v.Key), label)
+		}
+	}
+
+	for _, v := range vertices {
+		for _, c := range sortedVertices(v.Children) {
+			fmt.Fprintf(&b, "  %s --> %s\n", id(v.Key), id(c.Key))
+		}
+	}
+
+	return b.String()
+}
+
+func sortedVertices(vertices map[string]*manager.Vertex) []*manager.Vertex {
+	res := make([]*manager.Vertex, 0, len(vertices))
+	for _, v := range vertices {
+		res = append(res, v)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+
+	return res
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager"
+)
+
+func TestGraphRender(t *testing.T) {
+	project := &config.Project{
+		Terraform: map[string]*config.Terraform{
+			"infra": {},
+			"rds":   {DependsOn: []string{"infra"}},
+		},
+		Ecs: map[string]*config.Ecs{
+			"api":    {DependsOn: []string{"rds", "auth"}},
+			"auth":   {DependsOn: []string{"infra"}},
+			"worker": {},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		render func(*manager.Graph, *config.Project) string
+		want   string
+	}{
+		{
+			name:   "tree",
+			render: graphTree,
+			want: `api (ecs)
+├── auth (ecs)
+│   └── infra (terraform)
+└── rds (terraform)
+    └── infra (terraform)
+worker (ecs)
+├── infra (terraform)
+└── rds (terraform)
+    └── infra (terraform)
+`,
+		},
+		{
+			name:   "dot",
+			render: graphDOT,
+			want: `digraph ize {
+  rankdir=LR;
+  "api" [label="api (ecs)", shape=box];
+  "auth" [label="auth (ecs)", shape=box];
+  "infra" [label="infra (terraform)", shape=cylinder];
+  "rds" [label="rds (terraform)", shape=cylinder];
+  "worker" [label="worker (ecs)", shape=box];
+  "api" -> "auth";
+  "api" -> "rds";
+  "auth" -> "infra";
+  "rds" -> "infra";
+  "worker" -> "infra";
+  "worker" -> "rds";
+}
+`,
+		},
+		{
+			name:   "mermaid",
+			render: graphMermaid,
+			want: `graph TD
+  api["api (ecs)"]
+  auth["auth (ecs)"]
+  infra[("infra (terraform)")]
+  rds[("rds (terraform)")]
+  worker["worker (ecs)"]
+  api --> auth
+  api --> rds
+  auth --> infra
+  rds --> infra
+  worker --> infra
+  worker --> rds
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := manager.NewGraph(project.GetAll(), manager.AppStopped)
+			if got := tt.render(g, project); got != tt.want {
+				t.Errorf("render() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -74,9 +74,12 @@ func newRootCmd(project *config.Project) *cobra.Command {
 	cmd.AddCommand(
 		NewCmdBuild(project),
 		NewCmdDeploy(project),
+		NewCmdRollback(project),
 		NewCmdDown(project),
 		NewCmdConsole(project),
 		NewCmdTerraform(project),
+		NewCmdDrift(project),
+		NewCmdOutput(project),
 		NewCmdSecrets(project),
 		NewCmdInit(),
 		NewCmdTunnel(project),
@@ -86,6 +89,7 @@ func newRootCmd(project *config.Project) *cobra.Command {
 		NewCmdLogs(project),
 		NewDebugCmd(project),
 		NewCmdGen(project),
+		NewCmdGraph(project),
 		NewCmdPush(project),
 		NewCmdUp(project),
 		NewCmdNvm(project),
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+type OutputOptions struct {
+	Config *config.Project
+	Stack  string
+	Name   string
+	JSON   bool
+	out    io.Writer
+}
+
+var outputLongDesc = templates.LongDesc(`
+	Print outputs of a terraform stack stored by ize up.
+	Without an output name all outputs are printed, sensitive values are hidden unless --json is used.
+	With an output name the raw value is printed: strings as is, other types as JSON.
+`)
+
+var outputExample = templates.Examples(`
+	# Print all outputs of the infra stack
+	ize output infra
+
+	# Print the vpc_id output of the infra stack
+	ize output infra vpc_id
+
+	# Print all outputs of the vpc stack as JSON
+	ize output vpc --json
+`)
+
+func NewOutputFlags(project *config.Project) *OutputOptions {
+	return &OutputOptions{
+		Config: project,
+	}
+}
+
+func NewCmdOutput(project *config.Project) *cobra.Command {
+	o := NewOutputFlags(project)
+
+	cmd := &cobra.Command{
+		Use:     "output <stack> [name]",
+		Short:   "Print outputs of a terraform stack",
+		Long:    outputLongDesc,
+		Example: outputExample,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd, args)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.JSON, "json", false, "print outputs as JSON")
+
+	return cmd
+}
+
+func (o *OutputOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	o.Stack = args[0]
+	if len(args) == 2 {
+		o.Name = args[1]
+	}
+
+	o.out = cmd.OutOrStdout()
+
+	return nil
+}
+
+func (o *OutputOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("can't validate options: env must be specified")
+	}
+
+	if _, ok := o.Config.Terraform[o.Stack]; !ok {
+		return fmt.Errorf("can't validate options: terraform stack %s doesn't exist", o.Stack)
+	}
+
+	return nil
+}
+
+func (o *OutputOptions) Run() error {
+	outputs, err := getTerraformOutputs(o.Config.AWSClient.SSMClient, o.Config.Env, o.Stack)
+	if err != nil {
+		return err
+	}
+
+	if len(o.Name) != 0 {
+		output, ok := outputs[o.Name]
+		if !ok {
+			return fmt.Errorf("output %s of %s doesn't exist", o.Name, o.Stack)
+		}
+
+		return printOutputValue(o.out, output.Value)
+	}
+
+	if o.JSON {
+		b, err := json.MarshalIndent(outputs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("can't marshal outputs: %w", err)
+		}
+
+		_, err = fmt.Fprintf(o.out, "%s\n", b)
+		return err
+	}
+
+	return printOutputs(o.out, outputs)
+}
+
+func printOutputValue(w io.Writer, value interface{}) error {
+	if s, ok := value.(string); ok {
+		_, err := fmt.Fprintln(w, s)
+		return err
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("can't marshal output: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// printOutputs prints the outputs sorted by name like terraform output does.
+func printOutputs(w io.Writer, outputs map[string]stackOutput) error {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+
+	for _, name := range names {
+		value := "<sensitive>"
+		if !outputs[name].Sensitive {
+			b, err := json.Marshal(outputs[name].Value)
+			if err != nil {
+				return fmt.Errorf("can't marshal output %s: %w", name, err)
+			}
+			value = string(b)
+		}
+
+		fmt.Fprintf(tw, "%s\t= %s\n", name, value)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func TestOutputOptions_Run(t *testing.T) {
+	outputs := base64.StdEncoding.EncodeToString([]byte(`{
+		"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-0123"},
+		"private_subnets": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-a", "subnet-b"]},
+		"db_password": {"sensitive": true, "type": "string", "value": "secret"}
+	}`))
+
+	tests := []struct {
+		name    string
+		stack   string
+		output  string
+		mockSSM func(m *mocks.MockSSMAPI)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "all outputs",
+			stack: "vpc",
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(&ssm.GetParameterInput{
+					Name:           aws.String("/dev/terraform-output/vpc"),
+					WithDecryption: aws.Bool(true),
+				}).Return(&ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(outputs)}}, nil)
+			},
+			want: "db_password     = <sensitive>\nprivate_subnets = [\"subnet-a\",\"subnet-b\"]\nvpc_id          = \"vpc-0123\"\n",
+		},
+		{
+			name:   "string output",
+			stack:  "vpc",
+			output: "vpc_id",
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(gomock.Any()).Return(&ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(outputs)}}, nil)
+			},
+			want: "vpc-0123\n",
+		},
+		{
+			name:   "legacy infra outputs",
+			stack:  "infra",
+			output: "private_subnets",
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(&ssm.GetParameterInput{
+					Name:           aws.String("/dev/terraform-output/infra"),
+					WithDecryption: aws.Bool(true),
+				}).Return(nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil))
+				m.EXPECT().GetParameter(&ssm.GetParameterInput{
+					Name:           aws.String("/dev/terraform-output"),
+					WithDecryption: aws.Bool(true),
+				}).Return(&ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(outputs)}}, nil)
+			},
+			want: "[\"subnet-a\",\"subnet-b\"]\n",
+		},
+		{
+			name:   "missing output",
+			stack:  "vpc",
+			output: "zone_id",
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(gomock.Any()).Return(&ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(outputs)}}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "missing stack outputs",
+			stack: "vpc",
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(gomock.Any()).Return(nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSSMAPI := mocks.NewMockSSMAPI(ctrl)
+			tt.mockSSM(mockSSMAPI)
+
+			var out bytes.Buffer
+
+			o := &OutputOptions{
+				Config: &config.Project{
+					Env:       "dev",
+					AWSClient: config.NewAWSClient(config.WithSSMClient(mockSSMAPI)),
+				},
+				Stack: tt.stack,
+				Name:  tt.output,
+				out:   &out,
+			}
+
+			err := o.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("Run() output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
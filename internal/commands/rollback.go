@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/serverless"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	// rollbackRevisions is the number of revisions offered to pick from
+	rollbackRevisions = 10
+	// rollbackSearchRevisions is the number of revisions searched for --to
+	rollbackSearchRevisions = 50
+)
+
+type RollbackOptions struct {
+	Config  *config.Project
+	AppName string
+	To      string
+}
+
+var rollbackLongDesc = templates.LongDesc(`
+	Roll an app back to a previous release.
+	For ECS apps the recent task definition revisions are listed with their image tags and registration times,
+	pick one interactively or set it with --to (revision number or image tag). Inactive revisions are registered again.
+	For serverless apps --to is the timestamp of a deployment (see serverless deploy list), it's passed to serverless rollback.
+`)
+
+var rollbackExample = templates.Examples(`
+	# Pick the revision interactively (ECS only)
+	ize rollback <app name>
+
+	# Roll back to the task definition revision 42 (ECS only)
+	ize rollback <app name> --to 42
+
+	# Roll back to the revision with the image tag (ECS only)
+	ize rollback <app name> --to 1a2b3c4
+
+	# Roll back a serverless app to the deployment with the timestamp
+	ize rollback <app name> --to 1700000000000
+`)
+
+func NewRollbackFlags(project *config.Project) *RollbackOptions {
+	return &RollbackOptions{
+		Config: project,
+	}
+}
+
+func NewCmdRollback(project *config.Project) *cobra.Command {
+	o := NewRollbackFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "rollback [flags] <app name>",
+		Example:           rollbackExample,
+		Short:             "Roll an app back to a previous release",
+		Long:              rollbackLongDesc,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.To, "to", "", "set the release to roll back to: revision number or image tag (ECS), deployment timestamp (serverless)")
+
+	return cmd
+}
+
+func (o *RollbackOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	if _, ok := o.Config.Serverless[cmd.Flags().Args()[0]]; ok {
+		if err := requirements.CheckRequirements(requirements.WithNVM()); err != nil {
+			return err
+		}
+	}
+
+	o.AppName = cmd.Flags().Args()[0]
+
+	return nil
+}
+
+func (o *RollbackOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("can't validate options: env must be specified")
+	}
+
+	if len(o.Config.Namespace) == 0 {
+		return fmt.Errorf("can't validate options: namespace must be specified")
+	}
+
+	if len(o.AppName) == 0 {
+		return fmt.Errorf("can't validate options: app name must be specified")
+	}
+
+	_, isEcs := o.Config.Ecs[o.AppName]
+	_, isServerless := o.Config.Serverless[o.AppName]
+
+	switch {
+	case isServerless && len(o.To) == 0:
+		return fmt.Errorf("can't validate options: --to must be specified for serverless apps")
+	case !isEcs && !isServerless:
+		return fmt.Errorf("can't validate options: rollback is supported for ecs and serverless apps only, %s is neither", o.AppName)
+	}
+
+	return nil
+}
+
+func (o *RollbackOptions) Run() error {
+	ui := terminal.ConsoleUI(aws.BackgroundContext(), o.Config.PlainText)
+
+	if app, ok := o.Config.Serverless[o.AppName]; ok {
+		app.Name = o.AppName
+		app.RollbackTimestamp = o.To
+
+		m := &serverless.Manager{
+			Project: o.Config,
+			App:     app,
+		}
+
+		if err := m.Redeploy(ui); err != nil {
+			return err
+		}
+
+		ui.Output("Rollback app %s completed\n", o.AppName, terminal.WithSuccessStyle())
+
+		return nil
+	}
+
+	app := o.Config.Ecs[o.AppName]
+	app.Name = o.AppName
+
+	m := &ecs.Manager{
+		Project: o.Config,
+		App:     app,
+	}
+
+	limit := rollbackRevisions
+	if len(o.To) != 0 {
+		limit = rollbackSearchRevisions
+	}
+
+	revisions, err := m.Revisions(limit)
+	if err != nil {
+		return fmt.Errorf("can't list revisions of %s: %w", o.AppName, err)
+	}
+
+	if len(revisions) == 0 {
+		return fmt.Errorf("can't rollback: no task definition revisions of %s found", o.AppName)
+	}
+
+	var revision ecs.Revision
+	if len(o.To) != 0 {
+		revision, err = findRevision(revisions, o.To)
+	} else {
+		revision, err = selectRevision(revisions)
+	}
+	if err != nil {
+		return fmt.Errorf("can't rollback: %w", err)
+	}
+
+	if revision.Current {
+		return fmt.Errorf("can't rollback: revision %d is deployed already", revision.Number)
+	}
+
+	ui.Output("Rolling back %s to %s:%d (%s)...\n", o.AppName, revision.Family, revision.Number, revision.Image, terminal.WithHeaderStyle())
+
+	if err = m.Rollback(ui, revision); err != nil {
+		return err
+	}
+
+	ui.Output("Rollback app %s completed\n", o.AppName, terminal.WithSuccessStyle())
+
+	return nil
+}
+
+// findRevision returns the revision with the number or, if there is no such
+// revision, the newest one with the image tag.
+func findRevision(revisions []ecs.Revision, to string) (ecs.Revision, error) {
+	if n, err := strconv.ParseInt(to, 10, 64); err == nil {
+		for _, r := range revisions {
+			if r.Number == n {
+				return r, nil
+			}
+		}
+	}
+
+	for _, r := range revisions {
+		if strings.TrimPrefix(r.Tag(), ":") == to {
+			return r, nil
+		}
+	}
+
+	return ecs.Revision{}, fmt.Errorf("there is no revision %s or revision with image tag %s", to, to)
+}
+
+func selectRevision(revisions []ecs.Revision) (ecs.Revision, error) {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return ecs.Revision{}, fmt.Errorf("--to must be specified when not running in a terminal")
+	}
+
+	options := make([]string, len(revisions))
+	for i, r := range revisions {
+		options[i] = formatRevision(r)
+	}
+
+	var selected int
+	err := survey.AskOne(&survey.Select{
+		Message: "Revision to roll back to:",
+		Options: options,
+	}, &selected)
+	if err != nil {
+		return ecs.Revision{}, err
+	}
+
+	return revisions[selected], nil
+}
+
+func formatRevision(r ecs.Revision) string {
+	s := fmt.Sprintf("%d  %s  %s", r.Number, r.Image, r.RegisteredAt.Local().Format("2006-01-02 15:04"))
+
+	switch {
+	case r.Current:
+		s += "  (current)"
+	case r.Status == "INACTIVE":
+		s += "  (inactive)"
+	}
+
+	return s
+}
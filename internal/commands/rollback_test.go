@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/hazelops/ize/internal/manager/ecs"
+)
+
+func Test_findRevision(t *testing.T) {
+	revisions := []ecs.Revision{
+		{Number: 12, Image: "registry.example.com:5000/testnut-api:c3d4e5f", Current: true},
+		{Number: 11, Image: "registry.example.com:5000/testnut-api:1a2b3c4"},
+		{Number: 10, Image: "registry.example.com:5000/testnut-api:1a2b3c4", Status: "INACTIVE"},
+		{Number: 9, Image: "registry.example.com:5000/testnut-api:20"},
+	}
+
+	tests := []struct {
+		name    string
+		to      string
+		want    int64
+		wantErr bool
+	}{
+		{name: "revision number", to: "10", want: 10},
+		{name: "newest revision with the tag", to: "1a2b3c4", want: 11},
+		{name: "numeric tag", to: "20", want: 9},
+		{name: "unknown", to: "deadbeef", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findRevision(revisions, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findRevision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Number != tt.want {
+				t.Errorf("findRevision() = %d, want %d", got.Number, tt.want)
+			}
+		})
+	}
+}
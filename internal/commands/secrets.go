@@ -18,6 +18,8 @@ func NewCmdSecrets(project *config.Project) *cobra.Command {
 		NewCmdSecretsPush(project),
 		NewCmdSecretsEdit(project),
 		NewCmdSecretsPull(project),
+		NewCmdSecretsDiff(project),
+		NewCmdSecretsSync(project),
 	)
 
 	return cmd
@@ -0,0 +1,502 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/hazelops/ize/internal/config"
+)
+
+const (
+	secretsBackendSSM            = "ssm"
+	secretsBackendSecretsManager = "secretsmanager"
+
+	// secretsModeJSON stores all secrets of an app as one JSON secret,
+	// secretsModePerKey stores a secret per key under the path.
+	secretsModeJSON   = "json"
+	secretsModePerKey = "per-key"
+)
+
+var errSecretExists = fmt.Errorf("secret already exists, you can use --force to overwrite it")
+
+// secretsBackend is a key-value storage of the secrets of an app.
+type secretsBackend interface {
+	// push stores the values under the path. Existing values are
+	// overwritten only if force is set.
+	push(app string, path string, values map[string]string, force bool) error
+	// pull returns the values stored under the path.
+	pull(path string) (map[string]string, error)
+	// rm removes the values stored under the path.
+	rm(path string) error
+	// update overwrites the changed values and removes the keys, other
+	// values under the path are kept.
+	update(app string, path string, changed map[string]string, removed []string) error
+}
+
+type secretsBackendOptions struct {
+	// Mode is the storage mode of the secretsmanager backend.
+	Mode string
+	// KMSKeyID is the KMS key used to encrypt the secrets, the AWS managed
+	// key of the service is used if it's empty.
+	KMSKeyID string
+	// IncludeStrings makes the ssm backend pull plaintext strings too.
+	IncludeStrings bool
+}
+
+func newSecretsBackend(backend string, project *config.Project, opts secretsBackendOptions) (secretsBackend, error) {
+	switch backend {
+	case secretsBackendSSM:
+		return &ssmSecretsBackend{
+			api:            project.AWSClient.SSMClient,
+			kmsKeyID:       opts.KMSKeyID,
+			includeStrings: opts.IncludeStrings,
+		}, nil
+	case secretsBackendSecretsManager:
+		mode := opts.Mode
+		if len(mode) == 0 {
+			mode = secretsModeJSON
+		}
+
+		if mode != secretsModeJSON && mode != secretsModePerKey {
+			return nil, fmt.Errorf("mode %s is not supported, use %s or %s", mode, secretsModeJSON, secretsModePerKey)
+		}
+
+		return &secretsManagerBackend{
+			api:      project.AWSClient.SecretsManagerClient,
+			mode:     mode,
+			kmsKeyID: opts.KMSKeyID,
+		}, nil
+	default:
+		return nil, fmt.Errorf("backend with type %s not found or not supported", backend)
+	}
+}
+
+// ssmSecretsBackend stores a SecureString parameter per key in SSM Parameter
+// Store.
+type ssmSecretsBackend struct {
+	api            ssmiface.SSMAPI
+	kmsKeyID       string
+	includeStrings bool
+}
+
+func (b *ssmSecretsBackend) push(app string, path string, values map[string]string, force bool) error {
+	for key, value := range values {
+		name := fmt.Sprintf("%s/%s", path, key)
+
+		input := &ssm.PutParameterInput{
+			Name:      aws.String(name),
+			Value:     aws.String(value),
+			Type:      aws.String(ssm.ParameterTypeSecureString),
+			Overwrite: aws.Bool(force),
+		}
+		if len(b.kmsKeyID) != 0 {
+			input.KeyId = aws.String(b.kmsKeyID)
+		}
+
+		_, err := b.api.PutParameter(input)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterAlreadyExists {
+				return errSecretExists
+			}
+			return err
+		}
+
+		_, err = b.api.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceId:   aws.String(name),
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			Tags: []*ssm.Tag{
+				{
+					Key:   aws.String("Application"),
+					Value: aws.String(app),
+				},
+				{
+					Key:   aws.String("EnvVarName"),
+					Value: aws.String(key),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *ssmSecretsBackend) pull(path string) (map[string]string, error) {
+	values := map[string]string{}
+
+	typeValues := []string{ssm.ParameterTypeSecureString}
+	if b.includeStrings {
+		typeValues = append(typeValues, ssm.ParameterTypeString)
+	}
+
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("Type"),
+				Values: aws.StringSlice(typeValues),
+			},
+		},
+	}
+
+	for {
+		out, err := b.api.GetParametersByPath(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, param := range out.Parameters {
+			p := strings.Split(*param.Name, "/")
+			values[p[len(p)-1]] = *param.Value
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	return values, nil
+}
+
+func (b *ssmSecretsBackend) rm(path string) error {
+	out, err := b.api.GetParametersByPath(&ssm.GetParametersByPathInput{
+		Path: aws.String(path),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(out.Parameters) == 0 {
+		return nil
+	}
+
+	var names []*string
+
+	for _, p := range out.Parameters {
+		names = append(names, p.Name)
+	}
+
+	_, err = b.api.DeleteParameters(&ssm.DeleteParametersInput{
+		Names: names,
+	})
+
+	return err
+}
+
+// ssmDeleteParametersLimit is the max number of names of a DeleteParameters
+// call.
+const ssmDeleteParametersLimit = 10
+
+func (b *ssmSecretsBackend) update(app string, path string, changed map[string]string, removed []string) error {
+	err := b.push(app, path, changed, true)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(removed); i += ssmDeleteParametersLimit {
+		var names []*string
+		for _, key := range removed[i:min(i+ssmDeleteParametersLimit, len(removed))] {
+			names = append(names, aws.String(fmt.Sprintf("%s/%s", path, key)))
+		}
+
+		_, err = b.api.DeleteParameters(&ssm.DeleteParametersInput{
+			Names: names,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretsManagerBackend stores the secrets in AWS Secrets Manager either as
+// one JSON secret named after the path or as a secret per key.
+type secretsManagerBackend struct {
+	api      secretsmanageriface.SecretsManagerAPI
+	mode     string
+	kmsKeyID string
+}
+
+// secretName returns the name of the secret of the path. Secrets Manager
+// names have no leading slash unlike SSM parameters.
+func secretName(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+func (b *secretsManagerBackend) push(app string, path string, values map[string]string, force bool) error {
+	if b.mode == secretsModeJSON {
+		value, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+		return b.putSecret(secretName(path), string(value), secretsManagerTags(app, ""), force)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := b.putSecret(fmt.Sprintf("%s/%s", secretName(path), key), values[key], secretsManagerTags(app, key), force)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretsManagerTags returns the tags of a secret of the app. The JSON secret
+// holds all keys of the app, so it has no EnvVarName tag.
+func secretsManagerTags(app string, key string) []*secretsmanager.Tag {
+	tags := []*secretsmanager.Tag{
+		{
+			Key:   aws.String("Application"),
+			Value: aws.String(app),
+		},
+	}
+
+	if len(key) != 0 {
+		tags = append(tags, &secretsmanager.Tag{
+			Key:   aws.String("EnvVarName"),
+			Value: aws.String(key),
+		})
+	}
+
+	return tags
+}
+
+// putSecret creates the secret or, if it exists and force is set, puts a new
+// version of its value.
+func (b *secretsManagerBackend) putSecret(name string, value string, tags []*secretsmanager.Tag, force bool) error {
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(value),
+		Tags:         tags,
+	}
+	if len(b.kmsKeyID) != 0 {
+		input.KmsKeyId = aws.String(b.kmsKeyID)
+	}
+
+	_, err := b.api.CreateSecret(input)
+	if err == nil {
+		return nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != secretsmanager.ErrCodeResourceExistsException {
+		return err
+	}
+
+	if !force {
+		return errSecretExists
+	}
+
+	if len(b.kmsKeyID) != 0 {
+		_, err = b.api.UpdateSecret(&secretsmanager.UpdateSecretInput{
+			SecretId: aws.String(name),
+			KmsKeyId: aws.String(b.kmsKeyID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = b.api.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(value),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.api.TagResource(&secretsmanager.TagResourceInput{
+		SecretId: aws.String(name),
+		Tags:     tags,
+	})
+
+	return err
+}
+
+func (b *secretsManagerBackend) pull(path string) (map[string]string, error) {
+	if b.mode == secretsModeJSON {
+		out, err := b.api.GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(secretName(path)),
+		})
+		if err != nil {
+			// a secret that was never pushed has no keys, like an SSM path
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+				return map[string]string{}, nil
+			}
+			return nil, err
+		}
+
+		var raw map[string]interface{}
+
+		err = json.Unmarshal([]byte(aws.StringValue(out.SecretString)), &raw)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s is not a JSON object: %w", secretName(path), err)
+		}
+
+		values := map[string]string{}
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				values[k] = s
+				continue
+			}
+
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = string(b)
+		}
+
+		return values, nil
+	}
+
+	names, err := b.listSecrets(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+
+	for _, name := range names {
+		out, err := b.api.GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(name),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		values[name[strings.LastIndex(name, "/")+1:]] = aws.StringValue(out.SecretString)
+	}
+
+	return values, nil
+}
+
+// listSecrets returns the names of the secrets of the keys under the path.
+func (b *secretsManagerBackend) listSecrets(path string) ([]string, error) {
+	prefix := secretName(path) + "/"
+
+	input := &secretsmanager.ListSecretsInput{
+		Filters: []*secretsmanager.Filter{
+			{
+				Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+				Values: aws.StringSlice([]string{prefix}),
+			},
+		},
+	}
+
+	var names []string
+
+	for {
+		out, err := b.api.ListSecrets(input)
+		if err != nil {
+			return nil, err
+		}
+
+		// the name filter matches prefixes of words, so the secrets of
+		// nested paths are skipped here
+		for _, s := range out.SecretList {
+			name := aws.StringValue(s.Name)
+			if strings.HasPrefix(name, prefix) && !strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+				names = append(names, name)
+			}
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	return names, nil
+}
+
+func (b *secretsManagerBackend) rm(path string) error {
+	names := []string{secretName(path)}
+
+	if b.mode == secretsModePerKey {
+		var err error
+
+		names, err = b.listSecrets(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		// secrets are deleted without the recovery window, so they can be
+		// pushed again right away like SSM parameters
+		_, err := b.api.DeleteSecret(&secretsmanager.DeleteSecretInput{
+			SecretId:                   aws.String(name),
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *secretsManagerBackend) update(app string, path string, changed map[string]string, removed []string) error {
+	if b.mode == secretsModeJSON {
+		// the JSON secret is replaced as a whole, so the kept values are
+		// read first
+		values, err := b.pull(path)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range changed {
+			values[key] = value
+		}
+
+		for _, key := range removed {
+			delete(values, key)
+		}
+
+		return b.push(app, path, values, true)
+	}
+
+	err := b.push(app, path, changed, true)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range removed {
+		_, err := b.api.DeleteSecret(&secretsmanager.DeleteSecretInput{
+			SecretId:                   aws.String(fmt.Sprintf("%s/%s", secretName(path), key)),
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func Test_newSecretsBackend(t *testing.T) {
+	project := &config.Project{AWSClient: config.NewAWSClient()}
+
+	tests := []struct {
+		name    string
+		backend string
+		opts    secretsBackendOptions
+		want    secretsBackend
+		wantErr bool
+	}{
+		{
+			name:    "ssm",
+			backend: "ssm",
+			opts:    secretsBackendOptions{KMSKeyID: "alias/test", IncludeStrings: true},
+			want:    &ssmSecretsBackend{kmsKeyID: "alias/test", includeStrings: true},
+		},
+		{
+			name:    "secretsmanager defaults to json",
+			backend: "secretsmanager",
+			want:    &secretsManagerBackend{mode: secretsModeJSON},
+		},
+		{
+			name:    "secretsmanager per key",
+			backend: "secretsmanager",
+			opts:    secretsBackendOptions{Mode: "per-key", KMSKeyID: "alias/test"},
+			want:    &secretsManagerBackend{mode: secretsModePerKey, kmsKeyID: "alias/test"},
+		},
+		{
+			name:    "invalid mode",
+			backend: "secretsmanager",
+			opts:    secretsBackendOptions{Mode: "yaml"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid backend",
+			backend: "vault",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newSecretsBackend(tt.backend, project, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSecretsBackend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newSecretsBackend() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ssmSecretsBackend_push(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockSSMAPI(ctrl)
+	m.EXPECT().PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String("/test/squibby/KEY"),
+		Value:     aws.String("value"),
+		Type:      aws.String(ssm.ParameterTypeSecureString),
+		Overwrite: aws.Bool(false),
+		KeyId:     aws.String("alias/test"),
+	}).Return(&ssm.PutParameterOutput{}, nil)
+	m.EXPECT().AddTagsToResource(&ssm.AddTagsToResourceInput{
+		ResourceId:   aws.String("/test/squibby/KEY"),
+		ResourceType: aws.String("Parameter"),
+		Tags: []*ssm.Tag{
+			{Key: aws.String("Application"), Value: aws.String("squibby")},
+			{Key: aws.String("EnvVarName"), Value: aws.String("KEY")},
+		},
+	}).Return(&ssm.AddTagsToResourceOutput{}, nil)
+
+	b := &ssmSecretsBackend{api: m, kmsKeyID: "alias/test"}
+
+	err := b.push("squibby", "/test/squibby", map[string]string{"KEY": "value"}, false)
+	if err != nil {
+		t.Fatalf("push() error = %v", err)
+	}
+}
+
+func Test_secretsManagerBackend_push(t *testing.T) {
+	exists := awserr.New(secretsmanager.ErrCodeResourceExistsException, "exists", nil)
+
+	tests := []struct {
+		name    string
+		mode    string
+		force   bool
+		mock    func(m *mocks.MockSecretsManagerAPI)
+		wantErr bool
+	}{
+		{
+			name: "json",
+			mode: secretsModeJSON,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().CreateSecret(&secretsmanager.CreateSecretInput{
+					Name:         aws.String("test/squibby"),
+					SecretString: aws.String(`{"A":"1","B":"2"}`),
+					KmsKeyId:     aws.String("alias/test"),
+					Tags: []*secretsmanager.Tag{
+						{Key: aws.String("Application"), Value: aws.String("squibby")},
+					},
+				}).Return(&secretsmanager.CreateSecretOutput{}, nil)
+			},
+		},
+		{
+			name: "per key",
+			mode: secretsModePerKey,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				for _, kv := range [][2]string{{"A", "1"}, {"B", "2"}} {
+					m.EXPECT().CreateSecret(&secretsmanager.CreateSecretInput{
+						Name:         aws.String("test/squibby/" + kv[0]),
+						SecretString: aws.String(kv[1]),
+						KmsKeyId:     aws.String("alias/test"),
+						Tags: []*secretsmanager.Tag{
+							{Key: aws.String("Application"), Value: aws.String("squibby")},
+							{Key: aws.String("EnvVarName"), Value: aws.String(kv[0])},
+						},
+					}).Return(&secretsmanager.CreateSecretOutput{}, nil)
+				}
+			},
+		},
+		{
+			name: "exists",
+			mode: secretsModeJSON,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().CreateSecret(gomock.Any()).Return(nil, exists)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "exists with force",
+			mode:  secretsModeJSON,
+			force: true,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().CreateSecret(gomock.Any()).Return(nil, exists)
+				m.EXPECT().UpdateSecret(&secretsmanager.UpdateSecretInput{
+					SecretId: aws.String("test/squibby"),
+					KmsKeyId: aws.String("alias/test"),
+				}).Return(&secretsmanager.UpdateSecretOutput{}, nil)
+				m.EXPECT().PutSecretValue(&secretsmanager.PutSecretValueInput{
+					SecretId:     aws.String("test/squibby"),
+					SecretString: aws.String(`{"A":"1","B":"2"}`),
+				}).Return(&secretsmanager.PutSecretValueOutput{}, nil)
+				m.EXPECT().TagResource(gomock.Any()).Return(&secretsmanager.TagResourceOutput{}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockSecretsManagerAPI(ctrl)
+			tt.mock(m)
+
+			b := &secretsManagerBackend{api: m, mode: tt.mode, kmsKeyID: "alias/test"}
+
+			err := b.push("squibby", "/test/squibby", map[string]string{"A": "1", "B": "2"}, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_secretsManagerBackend_pull(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		mock func(m *mocks.MockSecretsManagerAPI)
+		want map[string]string
+	}{
+		{
+			name: "json",
+			mode: secretsModeJSON,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().GetSecretValue(&secretsmanager.GetSecretValueInput{
+					SecretId: aws.String("test/squibby"),
+				}).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"A":"1","PORT":8080}`),
+				}, nil)
+			},
+			want: map[string]string{"A": "1", "PORT": "8080"},
+		},
+		{
+			name: "per key",
+			mode: secretsModePerKey,
+			mock: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().ListSecrets(gomock.Any()).Return(&secretsmanager.ListSecretsOutput{
+					SecretList: []*secretsmanager.SecretListEntry{
+						{Name: aws.String("test/squibby/A")},
+						{Name: aws.String("test/squibby/nested/B")},
+					},
+					NextToken: aws.String("next"),
+				}, nil)
+				m.EXPECT().ListSecrets(gomock.Any()).Return(&secretsmanager.ListSecretsOutput{
+					SecretList: []*secretsmanager.SecretListEntry{
+						{Name: aws.String("test/squibby/C")},
+					},
+				}, nil)
+				m.EXPECT().GetSecretValue(&secretsmanager.GetSecretValueInput{
+					SecretId: aws.String("test/squibby/A"),
+				}).Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String("1")}, nil)
+				m.EXPECT().GetSecretValue(&secretsmanager.GetSecretValueInput{
+					SecretId: aws.String("test/squibby/C"),
+				}).Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String("3")}, nil)
+			},
+			want: map[string]string{"A": "1", "C": "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockSecretsManagerAPI(ctrl)
+			tt.mock(m)
+
+			b := &secretsManagerBackend{api: m, mode: tt.mode}
+
+			got, err := b.pull("/test/squibby")
+			if err != nil {
+				t.Fatalf("pull() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pull() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_secretsManagerBackend_rm(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockSecretsManagerAPI(ctrl)
+	m.EXPECT().ListSecrets(gomock.Any()).Return(&secretsmanager.ListSecretsOutput{
+		SecretList: []*secretsmanager.SecretListEntry{
+			{Name: aws.String("test/squibby/A")},
+			{Name: aws.String("test/squibby/B")},
+		},
+	}, nil)
+	m.EXPECT().DeleteSecret(&secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String("test/squibby/A"),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	}).Return(&secretsmanager.DeleteSecretOutput{}, nil)
+	m.EXPECT().DeleteSecret(&secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String("test/squibby/B"),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	}).Return(nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil))
+
+	b := &secretsManagerBackend{api: m, mode: secretsModePerKey}
+
+	err := b.rm("/test/squibby")
+	if err != nil {
+		t.Fatalf("rm() error = %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+type SecretsDiffOptions struct {
+	Config         *config.Project
+	AppName        string
+	Backend        string
+	FilePath       string
+	Format         string
+	SecretsPath    string
+	Mode           string
+	IncludeStrings bool
+	ShowValues     bool
+	out            io.Writer
+}
+
+var secretsDiffLongDesc = templates.LongDesc(`
+	Show the difference between a local secrets file and a key-value storage.
+	Keys that are only in the file are added, keys with other values are changed and keys that are
+	only in the storage are removed by ize secrets sync. Values are masked unless --show-values is used.
+`)
+
+var secretsDiffExample = templates.Examples(`
+	# Show keys of "squibby" app that differ from SSM
+	ize secrets diff squibby
+
+	# Show keys and values that differ from a Secrets Manager secret
+	ize secrets diff squibby --backend secretsmanager --show-values
+`)
+
+// secretsDiff is the difference between local and stored secrets, the keys
+// are sorted.
+type secretsDiff struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d secretsDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+func diffSecrets(local map[string]string, remote map[string]string) secretsDiff {
+	var d secretsDiff
+
+	for key, value := range local {
+		remoteValue, ok := remote[key]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, key)
+		case remoteValue != value:
+			d.Changed = append(d.Changed, key)
+		}
+	}
+
+	for key := range remote {
+		if _, ok := local[key]; !ok {
+			d.Removed = append(d.Removed, key)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Changed)
+	sort.Strings(d.Removed)
+
+	return d
+}
+
+// printSecretsDiff prints the diff like a plan: + added, ~ changed and -
+// removed keys.
+func printSecretsDiff(w io.Writer, d secretsDiff, local map[string]string, remote map[string]string, showValues bool) {
+	value := func(v string) string {
+		if showValues {
+			return fmt.Sprintf("%q", v)
+		}
+		return "<sensitive>"
+	}
+
+	for _, key := range d.Added {
+		fmt.Fprintf(w, "+ %s = %s\n", key, value(local[key]))
+	}
+
+	for _, key := range d.Changed {
+		fmt.Fprintf(w, "~ %s = %s -> %s\n", key, value(remote[key]), value(local[key]))
+	}
+
+	for _, key := range d.Removed {
+		fmt.Fprintf(w, "- %s = %s\n", key, value(remote[key]))
+	}
+
+	fmt.Fprintf(w, "\n%d to add, %d to change, %d to remove.\n", len(d.Added), len(d.Changed), len(d.Removed))
+}
+
+// loadSecretsDiff reads the local file and the stored secrets of the path and
+// returns both along with their difference.
+func loadSecretsDiff(project *config.Project, backend secretsBackend, path string, filePath string, format string) (map[string]string, map[string]string, secretsDiff, error) {
+	local, err := getKeyValuePairs(project, filePath, format)
+	if err != nil {
+		return nil, nil, secretsDiff{}, fmt.Errorf("can't read %s: %w", filePath, err)
+	}
+
+	remote, err := backend.pull(path)
+	if err != nil {
+		return nil, nil, secretsDiff{}, fmt.Errorf("can't pull secrets: %w", err)
+	}
+
+	return local, remote, diffSecrets(local, remote), nil
+}
+
+func NewSecretsDiffFlags(project *config.Project) *SecretsDiffOptions {
+	return &SecretsDiffOptions{
+		Config: project,
+	}
+}
+
+func NewCmdSecretsDiff(project *config.Project) *cobra.Command {
+	o := NewSecretsDiffFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "diff <app>",
+		Example:           secretsDiffExample,
+		Short:             "Show the difference between a secrets file and a key-value storage",
+		Long:              secretsDiffLongDesc,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type: ssm or secretsmanager (default=ssm)")
+	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.Format, "format", "", "format of the file: json, dotenv or yaml (detected by the file extension by default)")
+	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where secrets are stored (/<env>/<app> by default)")
+	cmd.Flags().StringVar(&o.Mode, "mode", secretsModeJSON, "secretsmanager storage mode: json (one secret with all keys) or per-key (a secret per key)")
+	cmd.Flags().BoolVar(&o.IncludeStrings, "include-strings", true, "compare plaintext SSM strings too, otherwise keys stored as strings are shown as added")
+	cmd.Flags().BoolVar(&o.ShowValues, "show-values", false, "show values instead of masking them")
+
+	return cmd
+}
+
+func (o *SecretsDiffOptions) Complete(cmd *cobra.Command) error {
+	o.AppName = cmd.Flags().Args()[0]
+
+	if o.FilePath == "" {
+		o.FilePath = fmt.Sprintf("%s/%s/%s%s", o.Config.EnvDir, "secrets", o.AppName, secretsFileExt(o.Format))
+	}
+
+	format, err := secretsFileFormat(o.FilePath, o.Format)
+	if err != nil {
+		return err
+	}
+	o.Format = format
+
+	if o.SecretsPath == "" {
+		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
+	}
+
+	o.out = cmd.OutOrStdout()
+
+	return nil
+}
+
+func (o *SecretsDiffOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("env must be specified")
+	}
+
+	return nil
+}
+
+func (o *SecretsDiffOptions) Run() error {
+	backend, err := newSecretsBackend(o.Backend, o.Config, secretsBackendOptions{
+		Mode:           o.Mode,
+		IncludeStrings: o.IncludeStrings,
+	})
+	if err != nil {
+		return err
+	}
+
+	local, remote, d, err := loadSecretsDiff(o.Config, backend, o.SecretsPath, o.FilePath, o.Format)
+	if err != nil {
+		return fmt.Errorf("can't diff secrets: %w", err)
+	}
+
+	if d.empty() {
+		fmt.Fprintf(o.out, "No changes, %s matches %s://%s\n", o.FilePath, o.Backend, o.SecretsPath)
+		return nil
+	}
+
+	printSecretsDiff(o.out, d, local, remote, o.ShowValues)
+
+	return nil
+}
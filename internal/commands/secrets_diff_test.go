@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+func Test_diffSecrets(t *testing.T) {
+	local := map[string]string{"A": "1", "B": "2", "D": "4", "C": "3"}
+	remote := map[string]string{"B": "2", "C": "old", "E": "5"}
+
+	want := secretsDiff{
+		Added:   []string{"A", "D"},
+		Changed: []string{"C"},
+		Removed: []string{"E"},
+	}
+
+	got := diffSecrets(local, remote)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSecrets() = %#v, want %#v", got, want)
+	}
+
+	if !diffSecrets(local, local).empty() {
+		t.Errorf("diffSecrets() of the same secrets is not empty")
+	}
+}
+
+func Test_printSecretsDiff(t *testing.T) {
+	local := map[string]string{"A": "1", "C": "3"}
+	remote := map[string]string{"C": "old", "E": "5"}
+	d := diffSecrets(local, remote)
+
+	tests := []struct {
+		name       string
+		showValues bool
+		want       string
+	}{
+		{
+			name: "masked",
+			want: `+ A = <sensitive>
+~ C = <sensitive> -> <sensitive>
+- E = <sensitive>
+
+1 to add, 1 to change, 1 to remove.
+`,
+		},
+		{
+			name:       "values",
+			showValues: true,
+			want: `+ A = "1"
+~ C = "old" -> "3"
+- E = "5"
+
+1 to add, 1 to change, 1 to remove.
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			printSecretsDiff(&b, d, local, remote, tt.showValues)
+
+			if b.String() != tt.want {
+				t.Errorf("printSecretsDiff() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretsSyncOptions_Run(t *testing.T) {
+	remote := &ssm.GetParametersByPathOutput{
+		Parameters: []*ssm.Parameter{
+			{Name: aws.String("/test/squibby/B"), Value: aws.String("2")},
+			{Name: aws.String("/test/squibby/C"), Value: aws.String("old")},
+			{Name: aws.String("/test/squibby/E"), Value: aws.String("5")},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		prune       bool
+		dryRun      bool
+		autoApprove bool
+		mock        func(m *mocks.MockSSMAPI)
+		wantErr     bool
+	}{
+		{
+			name: "push changes only",
+			mock: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(remote, nil)
+				for _, kv := range [][2]string{{"A", "1"}, {"C", "3"}} {
+					m.EXPECT().PutParameter(&ssm.PutParameterInput{
+						Name:      aws.String("/test/squibby/" + kv[0]),
+						Value:     aws.String(kv[1]),
+						Type:      aws.String(ssm.ParameterTypeSecureString),
+						Overwrite: aws.Bool(true),
+					}).Return(&ssm.PutParameterOutput{}, nil)
+				}
+				m.EXPECT().AddTagsToResource(gomock.Any()).Return(&ssm.AddTagsToResourceOutput{}, nil).Times(2)
+			},
+		},
+		{
+			name:   "dry run",
+			prune:  true,
+			dryRun: true,
+			mock: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(remote, nil)
+			},
+		},
+		{
+			name:  "prune without confirmation",
+			prune: true,
+			mock: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(remote, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:        "prune",
+			prune:       true,
+			autoApprove: true,
+			mock: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(remote, nil)
+				m.EXPECT().PutParameter(gomock.Any()).Return(&ssm.PutParameterOutput{}, nil).Times(2)
+				m.EXPECT().AddTagsToResource(gomock.Any()).Return(&ssm.AddTagsToResourceOutput{}, nil).Times(2)
+				m.EXPECT().DeleteParameters(&ssm.DeleteParametersInput{
+					Names: aws.StringSlice([]string{"/test/squibby/E"}),
+				}).Return(&ssm.DeleteParametersOutput{}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockSSMAPI(ctrl)
+			tt.mock(m)
+
+			file := filepath.Join(t.TempDir(), "squibby.env")
+			err := os.WriteFile(file, []byte("A=1\nB=2\nC=3\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o := &SecretsSyncOptions{
+				Config:      &config.Project{Env: "test", AWSClient: config.NewAWSClient(config.WithSSMClient(m))},
+				AppName:     "squibby",
+				Backend:     "ssm",
+				FilePath:    file,
+				Format:      "dotenv",
+				SecretsPath: "/test/squibby",
+				Prune:       tt.prune,
+				DryRun:      tt.dryRun,
+				AutoApprove: tt.autoApprove,
+				ui:          terminal.ConsoleUI(context.Background(), true),
+			}
+
+			err = o.Run()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretsSyncOptions_Run_missingSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notFound := awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+
+	m := mocks.NewMockSecretsManagerAPI(ctrl)
+	m.EXPECT().GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String("test/squibby"),
+	}).Return(nil, notFound).Times(3)
+	m.EXPECT().CreateSecret(&secretsmanager.CreateSecretInput{
+		Name:         aws.String("test/squibby"),
+		SecretString: aws.String(`{"A":"1","B":"2"}`),
+		Tags: []*secretsmanager.Tag{
+			{Key: aws.String("Application"), Value: aws.String("squibby")},
+		},
+	}).Return(&secretsmanager.CreateSecretOutput{}, nil)
+
+	file := filepath.Join(t.TempDir(), "squibby.env")
+	err := os.WriteFile(file, []byte("A=1\nB=2\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := &config.Project{Env: "test", AWSClient: config.NewAWSClient(config.WithSecretsManagerClient(m))}
+
+	var out strings.Builder
+
+	d := &SecretsDiffOptions{
+		Config:      project,
+		AppName:     "squibby",
+		Backend:     secretsBackendSecretsManager,
+		FilePath:    file,
+		Format:      secretsFormatDotenv,
+		SecretsPath: "/test/squibby",
+		Mode:        secretsModeJSON,
+		out:         &out,
+	}
+
+	if err = d.Run(); err != nil {
+		t.Fatalf("diff Run() error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), "2 to add, 0 to change, 0 to remove.") {
+		t.Errorf("diff Run() printed %q", out.String())
+	}
+
+	o := &SecretsSyncOptions{
+		Config:      project,
+		AppName:     "squibby",
+		Backend:     secretsBackendSecretsManager,
+		FilePath:    file,
+		Format:      secretsFormatDotenv,
+		SecretsPath: "/test/squibby",
+		Mode:        secretsModeJSON,
+		ui:          terminal.ConsoleUI(context.Background(), true),
+	}
+
+	if err = o.Run(); err != nil {
+		t.Errorf("sync Run() error = %v", err)
+	}
+}
@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/sops"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +22,7 @@ type SecretsEditOptions struct {
 	Config   *config.Project
 	AppName  string
 	FilePath string
+	Format   string
 }
 
 var secretsEditExample = templates.Examples(`
@@ -27,6 +33,9 @@ var secretsEditExample = templates.Examples(`
 
     # This will open your secrets file with local text editor
 	ize secrets edit squibby --file example-service.json
+
+    # This will open the dotenv secrets file of "squibby" app
+	ize secrets edit squibby --format dotenv
 `)
 
 func NewSecretsEditFlags(project *config.Project) *SecretsEditOptions {
@@ -42,7 +51,7 @@ func NewCmdSecretsEdit(project *config.Project) *cobra.Command {
 		Use:               "edit <app>",
 		Example:           secretsEditExample,
 		Short:             "Edit secrets file",
-		Long:              "This command open secrets file in default text editor",
+		Long:              "This command open secrets file in default text editor. Encrypted files are decrypted to a temporary file, opened in $EDITOR and encrypted back",
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -68,6 +77,7 @@ func NewCmdSecretsEdit(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.Format, "format", "", "format of the file: json, dotenv or yaml (detected by the file extension by default)")
 
 	return cmd
 }
@@ -76,9 +86,15 @@ func (o *SecretsEditOptions) Complete(cmd *cobra.Command) error {
 	o.AppName = cmd.Flags().Args()[0]
 
 	if o.FilePath == "" {
-		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
+		o.FilePath = fmt.Sprintf("%s/%s/%s%s", o.Config.EnvDir, "secrets", o.AppName, secretsFileExt(o.Format))
 	}
 
+	format, err := secretsFileFormat(o.FilePath, o.Format)
+	if err != nil {
+		return err
+	}
+	o.Format = format
+
 	return nil
 }
 
@@ -98,6 +114,15 @@ func (o *SecretsEditOptions) Run() error {
 
 	checkSecretFolder(o.Config.EnvDir)
 
+	data, err := os.ReadFile(absPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	if sops.IsEncrypted(data) || !secretsKeys(o.Config).Empty() {
+		return o.editEncrypted(absPath, data)
+	}
+
 	f, err := os.OpenFile(absPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0660)
 	if err != nil {
 		return fmt.Errorf("can't secrets edit: %w", err)
@@ -139,9 +164,123 @@ func (o *SecretsEditOptions) Run() error {
 		return fmt.Errorf("can't secrets edit: %w", err)
 	}
 
+	// the file is saved anyway, so the changes aren't lost
+	if len(strings.TrimSpace(text)) != 0 {
+		_, err = parseSecrets([]byte(text), o.Format)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid %s file: %w", o.FilePath, o.Format, err)
+		}
+	}
+
 	return nil
 }
 
+// editEncrypted decrypts the file to a temporary file only the user can read,
+// opens it in $EDITOR and encrypts the result back to the file.
+func (o *SecretsEditOptions) editEncrypted(path string, data []byte) error {
+	values := map[string]string{}
+
+	if len(bytes.TrimSpace(data)) != 0 {
+		var err error
+
+		values, err = decodeSecretsFile(o.Config, data, o.Format)
+		if err != nil {
+			return fmt.Errorf("can't secrets edit: %w", err)
+		}
+	}
+
+	if secretsKeys(o.Config).Empty() {
+		return fmt.Errorf("can't secrets edit: %s is encrypted, set age or kms keys in the secrets section of ize.toml to encrypt it back", o.FilePath)
+	}
+
+	// checked before the editor is opened, so no changes are lost
+	err := checkEncryptedFormat(o.Config, o.Format)
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	plaintext, err := formatSecrets(values, o.Format)
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	// CreateTemp creates the file with 0600 permissions
+	tmp, err := os.CreateTemp("", "ize-secrets-*"+secretsFileExt(o.Format))
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	// the decrypted file is kept when the changes can't be saved, so they
+	// aren't lost
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(plaintext)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	err = runEditor(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("can't secrets edit: %w", err)
+	}
+
+	newValues, err := parseSecrets(edited, o.Format)
+	if err != nil {
+		keep = true
+		return fmt.Errorf("can't secrets edit: changes are not saved, the result is not a valid %s file, the decrypted changes are kept in %s, remove it when done: %w", o.Format, tmp.Name(), err)
+	}
+
+	// values are encrypted with new IVs every time, so an unchanged file is
+	// not rewritten to keep its diff clean
+	if sops.IsEncrypted(data) && reflect.DeepEqual(newValues, values) {
+		return nil
+	}
+
+	out, err := encodeSecretsFile(o.Config, newValues, o.Format)
+	if err == nil {
+		err = os.WriteFile(path, out, 0600)
+	}
+	if err != nil {
+		keep = true
+		return fmt.Errorf("can't secrets edit: changes are not saved, the decrypted changes are kept in %s, remove it when done: %w", tmp.Name(), err)
+	}
+
+	return nil
+}
+
+// runEditor opens the file in $VISUAL or $EDITOR, vim by default.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if len(editor) == 0 {
+		editor = os.Getenv("EDITOR")
+	}
+	if len(editor) == 0 {
+		editor = "vim"
+	}
+
+	args := strings.Fields(editor)
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
 func checkSecretFolder(dir string) {
 	secretsFolder := filepath.Join(dir, "secrets")
 	_, err := os.Stat(secretsFolder)
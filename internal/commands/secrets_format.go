@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	secretsFormatJSON   = "json"
+	secretsFormatDotenv = "dotenv"
+	secretsFormatYAML   = "yaml"
+)
+
+var (
+	dotenvKey        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	dotenvPlainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+)
+
+// secretsFileFormat returns the format of the secrets file. If the format is
+// not set it's detected by the extension of the file, JSON by default.
+func secretsFileFormat(path string, format string) (string, error) {
+	switch format {
+	case secretsFormatJSON, secretsFormatDotenv, secretsFormatYAML:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("format %s is not supported, use %s, %s or %s", format, secretsFormatJSON, secretsFormatDotenv, secretsFormatYAML)
+	}
+
+	base := filepath.Base(path)
+
+	switch {
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return secretsFormatYAML, nil
+	case strings.HasSuffix(base, ".env"), strings.HasPrefix(base, ".env"):
+		return secretsFormatDotenv, nil
+	default:
+		return secretsFormatJSON, nil
+	}
+}
+
+// secretsFileExt returns the extension of the default secrets file of the
+// format.
+func secretsFileExt(format string) string {
+	switch format {
+	case secretsFormatDotenv:
+		return ".env"
+	case secretsFormatYAML:
+		return ".yaml"
+	default:
+		return ".json"
+	}
+}
+
+// parseSecrets parses a flat object of secrets. Numbers and booleans are kept
+// as they're written, so 1.50 is read as "1.50" rather than "1.5".
+func parseSecrets(data []byte, format string) (map[string]string, error) {
+	switch format {
+	case secretsFormatDotenv:
+		return parseDotenv(data)
+	case secretsFormatYAML:
+		return parseYAMLSecrets(data)
+	default:
+		return parseJSONSecrets(data)
+	}
+}
+
+// formatSecrets returns the secrets in the format with sorted keys, so the
+// output is the same for the same secrets.
+func formatSecrets(values map[string]string, format string) ([]byte, error) {
+	switch format {
+	case secretsFormatDotenv:
+		return formatDotenv(values)
+	case secretsFormatYAML:
+		return formatYAMLSecrets(values)
+	default:
+		return formatJSONSecrets(values)
+	}
+}
+
+func parseJSONSecrets(data []byte) (map[string]string, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var raw map[string]interface{}
+
+	err := d.Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of %s must be a string, number or boolean", key)
+		}
+	}
+
+	return values, nil
+}
+
+func formatJSONSecrets(values map[string]string) ([]byte, error) {
+	var b bytes.Buffer
+
+	// encoding/json sorts the keys of maps
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+
+	err := e.Encode(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func parseYAMLSecrets(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+
+	if len(doc.Content) == 0 {
+		return values, nil
+	}
+
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("secrets must be a mapping")
+	}
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		key, value := m.Content[i], m.Content[i+1]
+
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		if value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			return nil, fmt.Errorf("value of %s must be a string, number or boolean", key.Value)
+		}
+
+		values[key.Value] = value.Value
+	}
+
+	return values, nil
+}
+
+func formatYAMLSecrets(values map[string]string) ([]byte, error) {
+	var b bytes.Buffer
+
+	// yaml.v3 sorts the keys of maps and quotes strings which would be read
+	// as other types, e.g. "8080" or "true"
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+
+	err := e.Encode(values)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// parseDotenv parses KEY=VALUE lines. Values can be single quoted (literal),
+// double quoted (with \n, \t, \", \\ and \$ escapes) or unquoted, quoted
+// values can span multiple lines. Lines starting with # and "export "
+// prefixes are ignored.
+func parseDotenv(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	line := 1
+
+	for len(s) != 0 {
+		var l string
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			l, s = s[:i], s[i+1:]
+		} else {
+			l, s = s, ""
+		}
+
+		start := line
+		line++
+
+		l = strings.TrimSpace(l)
+		if len(l) == 0 || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		l = strings.TrimPrefix(l, "export ")
+
+		i := strings.IndexByte(l, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", start)
+		}
+
+		key := strings.TrimSpace(l[:i])
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", start, key)
+		}
+
+		value := strings.TrimLeft(l[i+1:], " \t")
+
+		if len(value) == 0 || (value[0] != '"' && value[0] != '\'') {
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = value[:j]
+			}
+			values[key] = strings.TrimSpace(value)
+			continue
+		}
+
+		// the quoted value can continue on the next lines
+		quote := value[0]
+		rest := value[1:]
+		for {
+			end := closingQuote(rest, quote)
+			if end >= 0 {
+				tail := strings.TrimSpace(rest[end+1:])
+				if len(tail) != 0 && !strings.HasPrefix(tail, "#") {
+					return nil, fmt.Errorf("line %d: unexpected %q after the value of %s", line-1, tail, key)
+				}
+				rest = rest[:end]
+				break
+			}
+
+			if len(s) == 0 {
+				return nil, fmt.Errorf("line %d: unterminated value of %s", start, key)
+			}
+
+			var next string
+			if j := strings.IndexByte(s, '\n'); j >= 0 {
+				next, s = s[:j], s[j+1:]
+			} else {
+				next, s = s, ""
+			}
+			line++
+
+			rest += "\n" + next
+		}
+
+		if quote == '"' {
+			rest = unescapeDotenv(rest)
+		}
+
+		values[key] = rest
+	}
+
+	return values, nil
+}
+
+// closingQuote returns the index of the quote closing the value or -1.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// formatDotenv writes a KEY=VALUE line per secret. Values are written
+// unquoted if they're safe for shells, single quoted if they have no single
+// quotes and newlines and double quoted with escapes otherwise.
+func formatDotenv(values map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("key %q can't be written to a dotenv file", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, quoteDotenv(values[key]))
+	}
+
+	return b.Bytes(), nil
+}
+
+func quoteDotenv(value string) string {
+	if dotenvPlainValue.MatchString(value) {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+
+	return `"` + r.Replace(value) + `"`
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_secretsFileFormat(t *testing.T) {
+	tests := []struct {
+		path    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{path: "secrets/squibby.json", want: "json"},
+		{path: "secrets/squibby.yaml", want: "yaml"},
+		{path: "secrets/squibby.yml", want: "yaml"},
+		{path: "secrets/squibby.env", want: "dotenv"},
+		{path: ".env", want: "dotenv"},
+		{path: ".env.local", want: "dotenv"},
+		{path: "secrets/squibby", want: "json"},
+		{path: "secrets/squibby.json", format: "dotenv", want: "dotenv"},
+		{path: "secrets/squibby.json", format: "toml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+tt.format, func(t *testing.T) {
+			got, err := secretsFileFormat(tt.path, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("secretsFileFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("secretsFileFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "json",
+			format: "json",
+			data:   `{"A": "one", "PORT": 8080, "RATE": 1.50, "DEBUG": false}`,
+			want:   map[string]string{"A": "one", "PORT": "8080", "RATE": "1.50", "DEBUG": "false"},
+		},
+		{
+			name:    "json nested",
+			format:  "json",
+			data:    `{"A": {"B": "c"}}`,
+			wantErr: true,
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			data:   "A: one\nPORT: 8080\nRATE: 1.50\nHEX: 0x1F\nQUOTED: \"8080\"\nCERT: |\n  line 1\n  line 2\n",
+			want:   map[string]string{"A": "one", "PORT": "8080", "RATE": "1.50", "HEX": "0x1F", "QUOTED": "8080", "CERT": "line 1\nline 2\n"},
+		},
+		{
+			name:    "yaml list",
+			format:  "yaml",
+			data:    "A:\n  - one\n",
+			wantErr: true,
+		},
+		{
+			name:   "dotenv",
+			format: "dotenv",
+			data: `# comment
+export A=one
+B = two words # comment
+C='single $HOME "quoted"'
+D="double \"quoted\"\n\$HOME"
+E="multi
+line"
+F=
+G=a#b
+`,
+			want: map[string]string{
+				"A": "one",
+				"B": "two words",
+				"C": `single $HOME "quoted"`,
+				"D": "double \"quoted\"\n$HOME",
+				"E": "multi\nline",
+				"F": "",
+				"G": "a#b",
+			},
+		},
+		{
+			name:    "dotenv unterminated",
+			format:  "dotenv",
+			data:    "A=\"one\nB=two\n",
+			wantErr: true,
+		},
+		{
+			name:    "dotenv invalid line",
+			format:  "dotenv",
+			data:    "A\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSecrets([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSecrets() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatSecrets(t *testing.T) {
+	values := map[string]string{
+		"PORT":  "8080",
+		"DEBUG": "true",
+		"NAME":  "squibby <dev>",
+		"CERT":  "line 1\nline 2",
+		"URL":   "postgres://user@host:5432/db",
+		"EMPTY": "",
+		"QUOTE": "it's $HOME",
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "json",
+			want: `{
+  "CERT": "line 1\nline 2",
+  "DEBUG": "true",
+  "EMPTY": "",
+  "NAME": "squibby <dev>",
+  "PORT": "8080",
+  "QUOTE": "it's $HOME",
+  "URL": "postgres://user@host:5432/db"
+}
+`,
+		},
+		{
+			format: "yaml",
+			want: `CERT: |-
+  line 1
+  line 2
+DEBUG: "true"
+EMPTY: ""
+NAME: squibby <dev>
+PORT: "8080"
+QUOTE: it's $HOME
+URL: postgres://user@host:5432/db
+`,
+		},
+		{
+			format: "dotenv",
+			want: `CERT="line 1\nline 2"
+DEBUG=true
+EMPTY=''
+NAME='squibby <dev>'
+PORT=8080
+QUOTE="it's \$HOME"
+URL=postgres://user@host:5432/db
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := formatSecrets(values, tt.format)
+			if err != nil {
+				t.Fatalf("formatSecrets() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatSecrets() = \n%s\nwant\n%s", got, tt.want)
+			}
+
+			// the values must be read back as they were
+			parsed, err := parseSecrets(got, tt.format)
+			if err != nil {
+				t.Fatalf("parseSecrets() error = %v", err)
+			}
+			if !reflect.DeepEqual(parsed, values) {
+				t.Errorf("parseSecrets() = %#v, want %#v", parsed, values)
+			}
+		})
+	}
+}
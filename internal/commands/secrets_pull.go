@@ -1,15 +1,11 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
 	"text/template"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -29,7 +25,9 @@ type SecretsPullOptions struct {
 	AppName        string
 	Backend        string
 	FilePath       string
+	Format         string
 	SecretsPath    string
+	Mode           string
 	Force          bool
 	Explain        bool
 	IncludeStrings bool
@@ -47,7 +45,7 @@ func NewCmdSecretsPull(project *config.Project) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:               "pull",
 		Short:             "Pull secrets to a a local file (like SSM)",
-		Long:              "This command pulls secrets from a key-value storage to a local file (like SSM)",
+		Long:              "This command pulls secrets from a key-value storage (SSM Parameter Store or Secrets Manager) to a local file",
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -72,9 +70,11 @@ func NewCmdSecretsPull(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type (default=ssm)")
+	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type: ssm or secretsmanager (default=ssm)")
 	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.Format, "format", "", "format of the file: json, dotenv or yaml (detected by the file extension by default)")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where to store secrets (/<env>/<app> by default)")
+	cmd.Flags().StringVar(&o.Mode, "mode", secretsModeJSON, "secretsmanager storage mode: json (one secret with all keys) or per-key (a secret per key)")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().BoolVar(&o.Force, "force", false, "allow values overwrite")
 	cmd.Flags().BoolVar(&o.IncludeStrings, "include-strings", false, "include plaintext strings")
@@ -86,9 +86,15 @@ func (o *SecretsPullOptions) Complete(cmd *cobra.Command) error {
 	o.AppName = cmd.Flags().Args()[0]
 
 	if o.FilePath == "" {
-		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
+		o.FilePath = fmt.Sprintf("%s/%s/%s%s", o.Config.EnvDir, "secrets", o.AppName, secretsFileExt(o.Format))
 	}
 
+	format, err := secretsFileFormat(o.FilePath, o.Format)
+	if err != nil {
+		return err
+	}
+	o.Format = format
+
 	if o.SecretsPath == "" {
 		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
 	}
@@ -118,14 +124,19 @@ func (o *SecretsPullOptions) Run() error {
 		return nil
 	}
 
+	backend, err := newSecretsBackend(o.Backend, o.Config, secretsBackendOptions{
+		Mode:           o.Mode,
+		IncludeStrings: o.IncludeStrings,
+	})
+	if err != nil {
+		return err
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Pulling secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.pull(s)
-		if err != nil {
-			return fmt.Errorf("can't pull secrets: %w", err)
-		}
-	} else {
-		return fmt.Errorf("backend with type %s not found or not supported", o.Backend)
+
+	err = o.pull(s, backend)
+	if err != nil {
+		return fmt.Errorf("can't pull secrets: %w", err)
 	}
 
 	s.Success("Pulling secrets complete!")
@@ -133,64 +144,15 @@ func (o *SecretsPullOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsPullOptions) pull(s *pterm.SpinnerPrinter) error {
+func (o *SecretsPullOptions) pull(s *pterm.SpinnerPrinter, backend secretsBackend) error {
 	s.UpdateText(fmt.Sprintf("Pulling secrets from %s://%s...", o.Backend, o.SecretsPath))
 
-	values := make(map[string]interface{})
-	typeValues := []string{"SecureString"}
-
-	if o.IncludeStrings {
-		typeValues = []string{"SecureString", "String"}
-	}
-
-	params, err := o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path:           aws.String(o.SecretsPath),
-		Recursive:      aws.Bool(true),
-		WithDecryption: aws.Bool(true),
-		ParameterFilters: []*ssm.ParameterStringFilter{
-			{
-				Key:    aws.String("Type"),
-				Values: aws.StringSlice(typeValues),
-			},
-		},
-	})
+	values, err := backend.pull(o.SecretsPath)
 	if err != nil {
 		return err
 	}
 
-	for _, param := range params.Parameters {
-		p := strings.Split(*param.Name, "/")
-		values[p[len(p)-1]] = *param.Value
-	}
-
-	for {
-		if params.NextToken == nil {
-			break
-		}
-
-		params, err = o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-			Path:           aws.String(o.SecretsPath),
-			Recursive:      aws.Bool(true),
-			WithDecryption: aws.Bool(true),
-			NextToken:      params.NextToken,
-			ParameterFilters: []*ssm.ParameterStringFilter{
-				{
-					Key:    aws.String("Type"),
-					Values: aws.StringSlice([]string{"SecureString"}),
-				},
-			},
-		})
-		if err != nil {
-			return err
-		}
-
-		for _, param := range params.Parameters {
-			p := strings.Split(*param.Name, "/")
-			values[p[len(p)-1]] = *param.Value
-		}
-	}
-
-	b, err := json.MarshalIndent(values, "", "")
+	b, err := encodeSecretsFile(o.Config, values, o.Format)
 	if err != nil {
 		return err
 	}
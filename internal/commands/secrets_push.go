@@ -1,16 +1,12 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"text/template"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/pterm/pterm"
@@ -22,7 +18,10 @@ type SecretsPushOptions struct {
 	AppName     string
 	Backend     string
 	FilePath    string
+	Format      string
 	SecretsPath string
+	Mode        string
+	KMSKeyID    string
 	Force       bool
 	Explain     bool
 }
@@ -45,6 +44,12 @@ var secretsPushExample = templates.Examples(`
     
     # This will push secrets for "squibby" app from a "example-service.json" file to the AWS SSM storage with force option (values will be overwritten if exist)
 	ize secrets push squibby --backend ssm --file example-service.json --force
+
+    # This will push secrets for "squibby" app from a dotenv file
+	ize secrets push squibby --file .env
+
+    # This will push secrets for "squibby" app to AWS Secrets Manager as one JSON secret encrypted with a custom KMS key
+	ize secrets push squibby --backend secretsmanager --kms-key-id alias/squibby
 `)
 
 func NewSecretsPushFlags(project *config.Project) *SecretsPushOptions {
@@ -60,7 +65,7 @@ func NewCmdSecretsPush(project *config.Project) *cobra.Command {
 		Use:               "push <app>",
 		Example:           secretsPushExample,
 		Short:             "Push secrets to a key-value storage (like SSM)",
-		Long:              "This command pushes secrets from a local file to a key-value storage (like SSM)",
+		Long:              "This command pushes secrets from a local file to a key-value storage (SSM Parameter Store or Secrets Manager)",
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -85,9 +90,12 @@ func NewCmdSecretsPush(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type (default=ssm)")
+	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type: ssm or secretsmanager (default=ssm)")
 	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.Format, "format", "", "format of the file: json, dotenv or yaml (detected by the file extension by default)")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where to store secrets (/<env>/<app> by default)")
+	cmd.Flags().StringVar(&o.Mode, "mode", secretsModeJSON, "secretsmanager storage mode: json (one secret with all keys) or per-key (a secret per key)")
+	cmd.Flags().StringVar(&o.KMSKeyID, "kms-key-id", "", "KMS key to encrypt secrets with (the AWS managed key by default)")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().BoolVar(&o.Force, "force", false, "allow values overwrite")
 
@@ -98,8 +106,14 @@ func (o *SecretsPushOptions) Complete(cmd *cobra.Command) error {
 	o.AppName = cmd.Flags().Args()[0]
 
 	if o.FilePath == "" {
-		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
+		o.FilePath = fmt.Sprintf("%s/%s/%s%s", o.Config.EnvDir, "secrets", o.AppName, secretsFileExt(o.Format))
+	}
+
+	format, err := secretsFileFormat(o.FilePath, o.Format)
+	if err != nil {
+		return err
 	}
+	o.Format = format
 
 	if o.SecretsPath == "" {
 		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
@@ -130,14 +144,19 @@ func (o *SecretsPushOptions) Run() error {
 		return nil
 	}
 
+	backend, err := newSecretsBackend(o.Backend, o.Config, secretsBackendOptions{
+		Mode:     o.Mode,
+		KMSKeyID: o.KMSKeyID,
+	})
+	if err != nil {
+		return err
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Pushing secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.push(s)
-		if err != nil {
-			return fmt.Errorf("can't push secrets: %w", err)
-		}
-	} else {
-		return fmt.Errorf("backend with type %s not found or not supported", o.Backend)
+
+	err = o.push(s, backend)
+	if err != nil {
+		return fmt.Errorf("can't push secrets: %w", err)
 	}
 
 	s.Success("Pushing secrets complete!")
@@ -145,58 +164,19 @@ func (o *SecretsPushOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsPushOptions) push(s *pterm.SpinnerPrinter) error {
+func (o *SecretsPushOptions) push(s *pterm.SpinnerPrinter, backend secretsBackend) error {
 	s.UpdateText("Reading secrets from file...")
-	values, err := getKeyValuePairs(o.FilePath)
+	values, err := getKeyValuePairs(o.Config, o.FilePath, o.Format)
 	if err != nil {
 		return err
 	}
 
 	s.UpdateText(fmt.Sprintf("Pushing secrets to %s://%s...", o.Backend, o.SecretsPath))
 
-	for key, value := range values {
-		name := fmt.Sprintf("%s/%s", o.SecretsPath, key)
-
-		_, err := o.Config.AWSClient.SSMClient.PutParameter(&ssm.PutParameterInput{
-			Name:      &name,
-			Value:     aws.String(value),
-			Type:      aws.String(ssm.ParameterTypeSecureString),
-			Overwrite: &o.Force,
-		})
-
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ParameterAlreadyExists":
-				return fmt.Errorf("secret already exists, you can use --force to overwrite it")
-			default:
-				return err
-			}
-		}
-
-		_, err = o.Config.AWSClient.SSMClient.AddTagsToResource(&ssm.AddTagsToResourceInput{
-			ResourceId:   &name,
-			ResourceType: aws.String("Parameter"),
-			Tags: []*ssm.Tag{
-				{
-					Key:   aws.String("Application"),
-					Value: &o.AppName,
-				},
-				{
-					Key:   aws.String("EnvVarName"),
-					Value: &key,
-				},
-			},
-		})
-
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return backend.push(o.AppName, o.SecretsPath, values, o.Force)
 }
 
-func getKeyValuePairs(filePath string) (map[string]string, error) {
+func getKeyValuePairs(project *config.Project, filePath string, format string) (map[string]string, error) {
 	if !filepath.IsAbs(filePath) {
 		var err error
 		wd, _ := os.Getwd()
@@ -229,11 +209,9 @@ func getKeyValuePairs(filePath string) (map[string]string, error) {
 		return nil, err
 	}
 
-	var result map[string]string
-
-	err = json.Unmarshal(bytes, &result)
+	result, err := decodeSecretsFile(project, bytes, format)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("can't parse %s as %s: %w", filePath, format, err)
 	}
 
 	return result, nil
@@ -6,7 +6,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
@@ -19,6 +18,7 @@ type SecretsRemoveOptions struct {
 	AppName     string
 	Backend     string
 	SecretsPath string
+	Mode        string
 	ui          terminal.UI
 	Explain     bool
 }
@@ -70,9 +70,10 @@ func NewCmdSecretsRemove(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type")
+	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type: ssm or secretsmanager")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path to secrets")
+	cmd.Flags().StringVar(&o.Mode, "mode", secretsModeJSON, "secretsmanager storage mode: json (one secret with all keys) or per-key (a secret per key)")
 
 	return cmd
 }
@@ -111,15 +112,18 @@ func (o *SecretsRemoveOptions) Run() error {
 		return nil
 	}
 
+	backend, err := newSecretsBackend(o.Backend, o.Config, secretsBackendOptions{
+		Mode: o.Mode,
+	})
+	if err != nil {
+		return err
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Removing secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.rm(s)
-		if err != nil {
-			pterm.DefaultSection.Sprintfln("Secrets have been removed from %s", o.SecretsPath)
-			return err
-		}
-	} else {
-		return fmt.Errorf("backend %s is not found or not supported", o.Backend)
+
+	err = o.rm(s, backend)
+	if err != nil {
+		return fmt.Errorf("can't remove secrets: %w", err)
 	}
 
 	s.Success("Removing secrets complete!")
@@ -127,7 +131,7 @@ func (o *SecretsRemoveOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter) error {
+func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter, backend secretsBackend) error {
 	if o.SecretsPath == "" {
 		s.UpdateText("Path was not set...")
 		time.Sleep(2 * time.Second)
@@ -136,37 +140,5 @@ func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter) error {
 
 	s.UpdateText(fmt.Sprintf("Removing secrets from %s://%s...", o.Backend, o.SecretsPath))
 
-	out, err := o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path: &o.SecretsPath,
-	})
-	if err != nil {
-		return err
-	}
-
-	s.UpdateText("Getting secrets...")
-
-	if len(out.Parameters) == 0 {
-		s.UpdateText("No values found...")
-		time.Sleep(2 * time.Second)
-		s.UpdateText("Removing secrets...")
-		time.Sleep(1 * time.Second)
-		return nil
-	}
-
-	var names []*string
-
-	for _, p := range out.Parameters {
-		names = append(names, p.Name)
-	}
-
-	_, err = o.Config.AWSClient.SSMClient.DeleteParameters(&ssm.DeleteParametersInput{
-		Names: names,
-	})
-	if err != nil {
-		return err
-	}
-
-	s.UpdateText("Removing secrets...")
-
-	return nil
+	return backend.rm(o.SecretsPath)
 }
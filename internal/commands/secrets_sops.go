@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/sops"
+)
+
+// secretsKeys returns the keys local secrets files are encrypted with, none
+// if the encryption is not configured in ize.toml.
+func secretsKeys(project *config.Project) sops.Keys {
+	if project.Secrets == nil {
+		return sops.Keys{}
+	}
+
+	return sops.Keys{
+		Age: project.Secrets.Age,
+		KMS: project.Secrets.KMS,
+	}
+}
+
+func kmsClient(project *config.Project) kmsiface.KMSAPI {
+	if project.AWSClient == nil {
+		return nil
+	}
+
+	return project.AWSClient.KMSClient
+}
+
+// encodeSecretsFile returns the content of a local secrets file. The values
+// are encrypted in the sops format if keys are set in ize.toml.
+func encodeSecretsFile(project *config.Project, values map[string]string, format string) ([]byte, error) {
+	keys := secretsKeys(project)
+	if keys.Empty() {
+		return formatSecrets(values, format)
+	}
+
+	err := checkEncryptedFormat(project, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return sops.Encrypt(values, keys, kmsClient(project))
+}
+
+// checkEncryptedFormat returns an error if files of the format can't be
+// encrypted with the keys of ize.toml.
+func checkEncryptedFormat(project *config.Project, format string) error {
+	if !secretsKeys(project).Empty() && format != secretsFormatJSON {
+		return fmt.Errorf("encrypted secrets files must be %s, not %s", secretsFormatJSON, format)
+	}
+
+	return nil
+}
+
+// decodeSecretsFile parses a local secrets file, encrypted files are
+// decrypted in memory.
+func decodeSecretsFile(project *config.Project, data []byte, format string) (map[string]string, error) {
+	if sops.IsEncrypted(data) {
+		values, err := sops.Decrypt(data, kmsClient(project))
+		if err != nil {
+			return nil, fmt.Errorf("can't decrypt: %w", err)
+		}
+
+		return values, nil
+	}
+
+	return parseSecrets(data, format)
+}
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/sops"
+)
+
+func newSecretsProject(t *testing.T) *config.Project {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(sops.AgeKeyEnv, identity.String())
+	t.Setenv(sops.AgeKeyFileEnv, filepath.Join(t.TempDir(), "keys.txt"))
+
+	return &config.Project{
+		Env:     "test",
+		EnvDir:  t.TempDir(),
+		Secrets: &config.Secrets{Age: []string{identity.Recipient().String()}},
+	}
+}
+
+func Test_encodeSecretsFile(t *testing.T) {
+	project := newSecretsProject(t)
+	values := map[string]string{"A": "one", "B": "two"}
+
+	data, err := encodeSecretsFile(project, values, secretsFormatJSON)
+	if err != nil {
+		t.Fatalf("encodeSecretsFile() error = %v", err)
+	}
+
+	if !sops.IsEncrypted(data) {
+		t.Fatalf("encodeSecretsFile() = %s is not encrypted", data)
+	}
+
+	got, err := decodeSecretsFile(project, data, secretsFormatJSON)
+	if err != nil {
+		t.Fatalf("decodeSecretsFile() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("decodeSecretsFile() = %v, want %v", got, values)
+	}
+
+	_, err = encodeSecretsFile(project, values, secretsFormatDotenv)
+	if err == nil {
+		t.Errorf("encodeSecretsFile() of an encrypted dotenv file succeeded")
+	}
+
+	data, err = encodeSecretsFile(&config.Project{}, values, secretsFormatDotenv)
+	if err != nil {
+		t.Fatalf("encodeSecretsFile() error = %v", err)
+	}
+
+	if string(data) != "A=one\nB=two\n" {
+		t.Errorf("encodeSecretsFile() without keys = %q", data)
+	}
+}
+
+func TestSecretsEditOptions_Run_encrypted(t *testing.T) {
+	project := newSecretsProject(t)
+	path := filepath.Join(project.EnvDir, "secrets", "squibby.json")
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := encodeSecretsFile(project, map[string]string{"A": "one", "B": "two"}, secretsFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/one/three/")
+
+	o := &SecretsEditOptions{Config: project, AppName: "squibby", FilePath: path, Format: secretsFormatJSON}
+
+	err = o.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := getKeyValuePairs(project, path, secretsFormatJSON)
+	if err != nil {
+		t.Fatalf("getKeyValuePairs() error = %v", err)
+	}
+
+	want := map[string]string{"A": "three", "B": "two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getKeyValuePairs() = %v, want %v", got, want)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sops.IsEncrypted(edited) {
+		t.Errorf("edited file is not encrypted:\n%s", edited)
+	}
+}
+
+func TestSecretsEditOptions_Run_encryptedDotenv(t *testing.T) {
+	project := newSecretsProject(t)
+	path := filepath.Join(project.EnvDir, "secrets", "squibby.env")
+	marker := filepath.Join(t.TempDir(), "opened")
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "touch "+marker)
+
+	o := &SecretsEditOptions{Config: project, AppName: "squibby", FilePath: path, Format: secretsFormatDotenv}
+
+	err := o.Run()
+	if err == nil || !strings.Contains(err.Error(), "must be json") {
+		t.Fatalf("Run() error = %v, want a format error", err)
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("Run() opened the editor before rejecting the format")
+	}
+}
+
+func TestSecretsEditOptions_Run_invalidEdit(t *testing.T) {
+	project := newSecretsProject(t)
+	path := filepath.Join(project.EnvDir, "secrets", "squibby.json")
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := encodeSecretsFile(project, map[string]string{"A": "one"}, secretsFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/one/three/;s/}/x/")
+
+	o := &SecretsEditOptions{Config: project, AppName: "squibby", FilePath: path, Format: secretsFormatJSON}
+
+	err = o.Run()
+	if err == nil {
+		t.Fatalf("Run() with an invalid result succeeded")
+	}
+
+	kept := regexp.MustCompile(`kept in (\S+),`).FindStringSubmatch(err.Error())
+	if kept == nil {
+		t.Fatalf("Run() error = %v doesn't name the kept file", err)
+	}
+	defer os.Remove(kept[1])
+
+	edited, err := os.ReadFile(kept[1])
+	if err != nil {
+		t.Fatalf("kept file: %v", err)
+	}
+
+	if !strings.Contains(string(edited), "three") {
+		t.Errorf("kept file = %s, want the changes", edited)
+	}
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+type SecretsSyncOptions struct {
+	Config         *config.Project
+	AppName        string
+	Backend        string
+	FilePath       string
+	Format         string
+	SecretsPath    string
+	Mode           string
+	KMSKeyID       string
+	IncludeStrings bool
+	ShowValues     bool
+	Prune          bool
+	DryRun         bool
+	AutoApprove    bool
+	ui             terminal.UI
+}
+
+var secretsSyncLongDesc = templates.LongDesc(`
+	Sync a key-value storage with a local secrets file.
+	Only added and changed keys are pushed. Keys that are only in the storage are kept unless --prune is used,
+	removing them must be confirmed unless --auto-approve is used.
+	With --dry-run the changes are shown like ize secrets diff does, but not applied.
+	Plaintext SSM strings are compared too, changed keys are always pushed as secure strings.
+`)
+
+var secretsSyncExample = templates.Examples(`
+	# Push added and changed secrets of "squibby" app to SSM
+	ize secrets sync squibby
+
+	# Show what would be changed, including removed keys
+	ize secrets sync squibby --prune --dry-run
+
+	# Make Secrets Manager secrets match the dotenv file exactly
+	ize secrets sync squibby --backend secretsmanager --mode per-key --file .env --prune
+`)
+
+func NewSecretsSyncFlags(project *config.Project) *SecretsSyncOptions {
+	return &SecretsSyncOptions{
+		Config: project,
+	}
+}
+
+func NewCmdSecretsSync(project *config.Project) *cobra.Command {
+	o := NewSecretsSyncFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "sync <app>",
+		Example:           secretsSyncExample,
+		Short:             "Sync a key-value storage with a secrets file",
+		Long:              secretsSyncLongDesc,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type: ssm or secretsmanager (default=ssm)")
+	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.Format, "format", "", "format of the file: json, dotenv or yaml (detected by the file extension by default)")
+	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where secrets are stored (/<env>/<app> by default)")
+	cmd.Flags().StringVar(&o.Mode, "mode", secretsModeJSON, "secretsmanager storage mode: json (one secret with all keys) or per-key (a secret per key)")
+	cmd.Flags().StringVar(&o.KMSKeyID, "kms-key-id", "", "KMS key to encrypt secrets with (the AWS managed key by default)")
+	cmd.Flags().BoolVar(&o.IncludeStrings, "include-strings", true, "compare plaintext SSM strings too, otherwise keys stored as strings are shown as added")
+	cmd.Flags().BoolVar(&o.ShowValues, "show-values", false, "show values instead of masking them")
+	cmd.Flags().BoolVar(&o.Prune, "prune", false, "remove keys that are not in the file")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "show changes without applying them")
+	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "remove keys without confirmation")
+
+	return cmd
+}
+
+func (o *SecretsSyncOptions) Complete(cmd *cobra.Command) error {
+	o.AppName = cmd.Flags().Args()[0]
+
+	if o.FilePath == "" {
+		o.FilePath = fmt.Sprintf("%s/%s/%s%s", o.Config.EnvDir, "secrets", o.AppName, secretsFileExt(o.Format))
+	}
+
+	format, err := secretsFileFormat(o.FilePath, o.Format)
+	if err != nil {
+		return err
+	}
+	o.Format = format
+
+	if o.SecretsPath == "" {
+		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
+	}
+
+	o.ui = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
+
+	return nil
+}
+
+func (o *SecretsSyncOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("env must be specified")
+	}
+
+	return nil
+}
+
+func (o *SecretsSyncOptions) Run() error {
+	backend, err := newSecretsBackend(o.Backend, o.Config, secretsBackendOptions{
+		Mode:           o.Mode,
+		KMSKeyID:       o.KMSKeyID,
+		IncludeStrings: o.IncludeStrings,
+	})
+	if err != nil {
+		return err
+	}
+
+	local, remote, d, err := loadSecretsDiff(o.Config, backend, o.SecretsPath, o.FilePath, o.Format)
+	if err != nil {
+		return fmt.Errorf("can't sync secrets: %w", err)
+	}
+
+	kept := 0
+	if !o.Prune {
+		kept = len(d.Removed)
+		d.Removed = nil
+	}
+
+	if d.empty() {
+		o.ui.Output("No changes, %s://%s matches %s", o.Backend, o.SecretsPath, o.FilePath, terminal.WithSuccessStyle())
+		return nil
+	}
+
+	var b strings.Builder
+	printSecretsDiff(&b, d, local, remote, o.ShowValues)
+
+	o.ui.Output("Secrets of %s in %s://%s will be changed:\n%s", o.AppName, o.Backend, o.SecretsPath, b.String())
+
+	if kept != 0 {
+		o.ui.Output("%d key(s) that are not in %s are kept, use --prune to remove them", kept, o.FilePath, terminal.WithWarningStyle())
+	}
+
+	if o.DryRun {
+		return nil
+	}
+
+	if len(d.Removed) != 0 && !o.AutoApprove {
+		answer, err := o.ui.Input(&terminal.Input{
+			Prompt: fmt.Sprintf("Do you want to remove %d key(s) of %s? Only 'yes' will be accepted:", len(d.Removed), o.AppName),
+			Style:  terminal.WarningStyle,
+		})
+		if err != nil {
+			if errors.Is(err, terminal.ErrNonInteractive) {
+				return fmt.Errorf("can't ask for confirmation, use --auto-approve to remove keys: %w", err)
+			}
+			return fmt.Errorf("can't ask for confirmation: %w", err)
+		}
+
+		if answer != "yes" {
+			return fmt.Errorf("sync of %s cancelled", o.AppName)
+		}
+	}
+
+	changed := map[string]string{}
+	for _, key := range append(d.Added, d.Changed...) {
+		changed[key] = local[key]
+	}
+
+	err = backend.update(o.AppName, o.SecretsPath, changed, d.Removed)
+	if err != nil {
+		return fmt.Errorf("can't sync secrets: %w", err)
+	}
+
+	o.ui.Output("Secrets of %s synced!\n", o.AppName, terminal.WithSuccessStyle())
+
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 )
 
 //go:generate mockgen -package=mocks -destination ../../pkg/mocks/mock_ssm.go github.com/aws/aws-sdk-go/service/ssm/ssmiface SSMAPI
+//go:generate mockgen -package=mocks -destination ../../pkg/mocks/mock_secretsmanager.go github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface SecretsManagerAPI
 
 //go:embed testdata/build_valid.toml
 var secretsToml string
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/terraform"
 
 	"github.com/hazelops/ize/internal/version"
 	"github.com/pterm/pterm"
@@ -45,16 +46,21 @@ func NewDebugCmd(project *config.Project) *cobra.Command {
 			}).WithLeftAlignment().Render()
 
 			v := project.TerraformVersion
+			engine := terraform.EngineTerraform
 			if project.Terraform != nil {
 				if i, ok := project.Terraform["infra"]; ok {
 					if len(i.Version) != 0 {
 						v = i.Version
 					}
+					if len(i.Engine) != 0 {
+						engine = i.Engine
+					}
 				}
 			}
 
 			pterm.DefaultSection.Println("Terraform Info")
 			_ = dt.WithData(pterm.TableData{
+				{"TERRAFORM_ENGINE", engine},
 				{"TERRAFORM_VERSION", v},
 			}).WithLeftAlignment().Render()
 
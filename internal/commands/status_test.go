@@ -67,6 +67,7 @@ func TestStatus(t *testing.T) {
 				"ENV            | test",
 				"NAMESPACE      | testnut",
 				"TAG            | test",
+				"TERRAFORM_ENGINE  | terraform",
 				"TERRAFORM_VERSION | 1.2.6",
 				"AWS PROFILE | test",
 				"AWS USER    | test",
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"github.com/hazelops/ize/internal/requirements"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/terraform"
 	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +25,8 @@ var terraformLongDesc = templates.LongDesc(`
 	Run terraform command via terraform docker container.
 	By default, terraform runs locally.
 	At the same time, terraform will be downloaded and launched from ~/.ize/versions/terraform/
+	If engine = "opentofu" is set for the infra stack, OpenTofu is used instead and downloaded to ~/.ize/versions/tofu/
+	Providers are cached in ~/.ize/plugin-cache/ (or TF_PLUGIN_CACHE_DIR) and shared by all stacks.
 
 	To use a docker terraform, set value of "docker" to the --prefer-runtime global flag.
 `)
@@ -79,6 +83,8 @@ func NewCmdTerraform(project *config.Project) *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(NewCmdTerraformLock(project))
+
 	return cmd
 }
 
@@ -137,6 +143,17 @@ func (o *TerraformOptions) Run(args []string) error {
 		return fmt.Errorf("can't supported %s runtime", o.Config.PreferRuntime)
 	}
 
+	// stacks with the workspace layout run in the workspace of the env, init
+	// and workspace commands are passed through as is
+	if o.Config.Terraform["infra"].IsWorkspaceLayout() && len(args) != 0 && args[0] != "init" && args[0] != "workspace" {
+		err = selectWorkspace(terminal.ConsoleUI(context.Background(), o.Config.PlainText), tf, o.Config, "infra")
+		if err != nil {
+			return fmt.Errorf("%w, run ize terraform init first", err)
+		}
+
+		tf.NewCmd(args)
+	}
+
 	logrus.Debug("starting terraform")
 
 	err = tf.Run()
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// defaultLockPlatforms are the platforms ize runs terraform on: CI runners and
+// laptops.
+var defaultLockPlatforms = []string{"darwin_amd64", "darwin_arm64", "linux_amd64", "linux_arm64"}
+
+type TerraformLockOptions struct {
+	Config    *config.Project
+	SkipGen   bool
+	Platforms []string
+	ui        terminal.UI
+}
+
+var terraformLockLongDesc = templates.LongDesc(`
+	Generate .terraform.lock.hcl of every terraform stack with terraform providers lock.
+	The lock files get the checksums of the providers for all platforms, so init is reproducible
+	on every platform and the providers can be installed from the shared plugin cache (~/.ize/plugin-cache).
+	The platforms are set by --platform, lock_platforms of the stack or default to darwin_amd64, darwin_arm64,
+	linux_amd64 and linux_arm64.
+`)
+
+var terraformLockExample = templates.Examples(`
+	# Lock the providers of all terraform stacks
+	ize terraform lock
+
+	# Lock the providers for Linux only
+	ize terraform lock --platform linux_amd64 --platform linux_arm64
+`)
+
+func NewTerraformLockFlags(project *config.Project) *TerraformLockOptions {
+	return &TerraformLockOptions{
+		Config: project,
+	}
+}
+
+func NewCmdTerraformLock(project *config.Project) *cobra.Command {
+	o := NewTerraformLockFlags(project)
+
+	cmd := &cobra.Command{
+		Use:     "lock",
+		Short:   "Generate lock files of terraform stacks for all platforms",
+		Long:    terraformLockLongDesc,
+		Example: terraformLockExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete()
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().StringArrayVar(&o.Platforms, "platform", nil, "platform to lock the providers for, e.g. linux_amd64 (can be repeated)")
+
+	return cmd
+}
+
+func (o *TerraformLockOptions) Complete() error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	if o.Config.Terraform == nil {
+		return fmt.Errorf("you must specify at least one terraform stack in ize.toml")
+	}
+
+	setTerraformDefaults(o.Config)
+
+	o.ui = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
+
+	return nil
+}
+
+func (o *TerraformLockOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("can't validate options: env must be specified")
+	}
+
+	return nil
+}
+
+func (o *TerraformLockOptions) Run() error {
+	names := make([]string, 0, len(o.Config.Terraform))
+	for name := range o.Config.Terraform {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		platforms := o.Platforms
+		if len(platforms) == 0 {
+			platforms = o.Config.Terraform[name].LockPlatforms
+		}
+		if len(platforms) == 0 {
+			platforms = defaultLockPlatforms
+		}
+
+		tf, err := newTerraform(name, o.Config, o.SkipGen)
+		if err != nil {
+			return fmt.Errorf("can't lock providers of %s: %w", name, err)
+		}
+
+		tf.NewCmd(lockCommand(platforms))
+
+		o.ui.Output("[%s] Locking providers...", name, terminal.WithHeaderStyle())
+
+		err = tf.RunUI(o.ui)
+		if err != nil {
+			return fmt.Errorf("can't lock providers of %s: %w", name, err)
+		}
+	}
+
+	o.ui.Output("Lock files of %d terraform stack(s) generated\n", len(names), terminal.WithSuccessStyle())
+
+	return nil
+}
+
+func lockCommand(platforms []string) []string {
+	cmd := []string{"providers", "lock"}
+	for _, p := range platforms {
+		cmd = append(cmd, fmt.Sprintf("-platform=%s", p))
+	}
+
+	return cmd
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func Test_lockCommand(t *testing.T) {
+	want := []string{"providers", "lock", "-platform=linux_amd64", "-platform=darwin_arm64"}
+	if got := lockCommand([]string{"linux_amd64", "darwin_arm64"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("lockCommand() = %v, want %v", got, want)
+	}
+}
+
+func TestCmdTerraform_lockSubcommand(t *testing.T) {
+	// terraform commands are passed through only by a subcommand, like in ize
+	root := &cobra.Command{Use: "ize"}
+	root.AddCommand(NewCmdTerraform(&config.Project{}))
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"lock", "--platform", "linux_amd64"}, want: "lock"},
+		{args: []string{"plan", "-out=tfplan"}, want: "terraform"},
+		{args: []string{"providers", "lock"}, want: "terraform"},
+	}
+	for _, tt := range tests {
+		got, _, err := root.Find(append([]string{"terraform"}, tt.args...))
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", tt.args, err)
+		}
+		if got.Name() != tt.want {
+			t.Errorf("Find(%v) = %s, want %s", tt.args, got.Name(), tt.want)
+		}
+	}
+}
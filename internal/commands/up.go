@@ -8,6 +8,7 @@ import (
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/internal/terraform"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/pterm/pterm"
@@ -21,7 +22,9 @@ type UpOptions struct {
 	SkipGen          bool
 	UseYarn          bool
 	AutoApprove      bool
+	AllowDestroy     bool
 	Explain          bool
+	Parallelism      int
 	UI               terminal.UI
 }
 
@@ -30,8 +33,10 @@ type Apps map[string]*interface{}
 var upLongDesc = templates.LongDesc(`
 	Deploy infrastructure or service.
     App name must be specified for a bringing it up.  
-	The infrastructure for the app must be ready to 
-	receive the deployment (generally created via ize infra up in CI/CD).
+	Terraform stacks and apps are brought up as a single dependency graph.
+	Apps may list terraform stacks in depends_on; apps that don't wait for
+	all terraform stacks. When an app or stack name is given, only it and
+	what it transitively depends on are brought up.
 `)
 
 var upExample = templates.Examples(`
@@ -41,6 +46,9 @@ var upExample = templates.Examples(`
 	# Deploy app (config file required)
 	ize up <app name>
 
+	# Deploy all, bringing up to 4 independent apps or stacks at a time
+	ize up --auto-approve --parallelism 4
+
 	# Deploy app with explicitly specified config file
 	ize --config-file (or -c) /path/to/config up <app name>
 
@@ -94,7 +102,9 @@ func NewCmdUp(project *config.Project) *cobra.Command {
 	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "approve deploy all")
 	cmd.Flags().BoolVar(&o.UseYarn, "use-yarn", false, "execute sls commands using yarn")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().BoolVar(&o.AllowDestroy, "allow-destroy", false, "apply terraform plans that violate the destroy policy of the stack")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 1, "number of apps and terraform stacks to bring up at the same time (0 means no limit)")
 
 	cmd.AddCommand(
 		NewCmdUpInfra(project),
@@ -113,20 +123,6 @@ func (o *UpOptions) Complete(cmd *cobra.Command, args []string) error {
 		if o.Config.Terraform == nil {
 			return fmt.Errorf("you must specify at least one terraform stack in ize.toml")
 		}
-
-		if _, ok := o.Config.Terraform["infra"]; ok {
-			if len(o.Config.Terraform["infra"].AwsProfile) == 0 {
-				o.Config.Terraform["infra"].AwsProfile = o.Config.AwsProfile
-			}
-
-			if len(o.Config.Terraform["infra"].AwsRegion) == 0 {
-				o.Config.Terraform["infra"].AwsRegion = o.Config.AwsRegion
-			}
-
-			if len(o.Config.Terraform["infra"].Version) == 0 {
-				o.Config.Terraform["infra"].Version = o.Config.TerraformVersion
-			}
-		}
 	} else {
 		if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
 			return err
@@ -135,6 +131,8 @@ func (o *UpOptions) Complete(cmd *cobra.Command, args []string) error {
 		o.AppName = cmd.Flags().Args()[0]
 	}
 
+	setTerraformDefaults(o.Config)
+
 	if len(o.Config.Serverless) != 0 {
 		if err := requirements.CheckRequirements(requirements.WithNVM()); err != nil {
 			return err
@@ -176,14 +174,11 @@ func (o *UpOptions) Run() error {
 			return err
 		}
 	} else {
-		if _, ok := o.Config.Terraform[o.AppName]; ok {
-			err := deployInfra(o.AppName, ui, o.Config, o.SkipGen)
-			if err != nil {
-				return err
-			}
+		if o.Explain {
+			return deployApp(o.AppName, ui, o.Config, true)
 		}
 
-		err := deployApp(o.AppName, ui, o.Config, o.Explain)
+		err := deployGraph(ui, o, o.Config.GetDependencies(o.AppName))
 		if err != nil {
 			return err
 		}
@@ -205,6 +200,10 @@ func (o *UpOptions) validate() error {
 		return fmt.Errorf("can't validate options: app name must be specified")
 	}
 
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism can't be negative")
+	}
+
 	return nil
 }
 
@@ -217,41 +216,56 @@ func (o *UpOptions) validateAll() error {
 		return fmt.Errorf("can't validate options: namespace must be specified")
 	}
 
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism can't be negative")
+	}
+
 	return nil
 }
 
 func deployAll(ui terminal.UI, o *UpOptions) error {
-	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := deployInfra("infra", ui, o.Config, o.SkipGen)
-		if err != nil {
-			return err
-		}
-	}
-
-	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		return deployInfra(name, ui, o.Config, o.SkipGen)
-	})
+	err := deployGraph(ui, o, o.Config.GetAll())
 	if err != nil {
 		return err
 	}
 
-	ui.Output("Deploying apps...", terminal.WithHeaderStyle())
+	ui.Output("Deploy all completed!\n", terminal.WithSuccessStyle())
 
-	err = manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
+	return nil
+}
 
-		err := deployApp(name, ui, o.Config, false)
-		if err != nil {
-			return err
+// deployGraph brings up terraform stacks and apps in dependency order.
+func deployGraph(ui terminal.UI, o *UpOptions, nodes map[string]*interface{}) error {
+	// apps are deployed with the profile of the infra stack
+	appsConfig := *o.Config
+	if v, ok := o.Config.Terraform["infra"]; ok {
+		appsConfig.AwsProfile = v.AwsProfile
+	}
+
+	return manager.InDependencyOrder(aws.BackgroundContext(), nodes, func(c context.Context, name string) error {
+		if _, ok := o.Config.Terraform[name]; ok {
+			return deployInfra(name, ui, o.Config, o.SkipGen, o.AutoApprove, o.AllowDestroy)
 		}
 
-		return nil
-	})
-	if err != nil {
-		return err
-	}
+		return deployApp(name, ui, &appsConfig, false)
+	}, manager.WithParallelism(o.Parallelism))
+}
 
-	ui.Output("Deploy all completed!\n", terminal.WithSuccessStyle())
+// setTerraformDefaults fills the aws profile, region and terraform version
+// of the stacks that don't set them with the values of the project. Opentofu
+// stacks get the default opentofu version instead.
+func setTerraformDefaults(project *config.Project) {
+	for name, tf := range project.Terraform {
+		if len(tf.AwsProfile) == 0 {
+			tf.AwsProfile = project.AwsProfile
+		}
 
-	return nil
+		if len(tf.AwsRegion) == 0 {
+			tf.AwsRegion = project.AwsRegion
+		}
+
+		if len(tf.Version) == 0 {
+			tf.Version = terraform.DefaultVersion(project, name)
+		}
+	}
 }
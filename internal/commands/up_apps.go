@@ -10,6 +10,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/helm"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/pkg/templates"
@@ -18,9 +19,10 @@ import (
 )
 
 type UpAppsOptions struct {
-	Config  *config.Project
-	UI      terminal.UI
-	Explain bool
+	Config      *config.Project
+	UI          terminal.UI
+	Explain     bool
+	Parallelism int
 }
 
 var upAppsLongDesc = templates.LongDesc(`
@@ -31,6 +33,9 @@ var upAppsExample = templates.Examples(`
 	# Up all apps
 	ize up apps
 
+	# Up apps, deploying up to 4 independent apps at a time
+	ize up apps --parallelism 4
+
 	# Up apps with explicitly specified config file
 	ize --config-file /path/to/config up apps
 
@@ -75,6 +80,7 @@ func NewCmdUpApps(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 1, "number of apps to bring up at the same time (0 means no limit)")
 
 	return cmd
 }
@@ -96,30 +102,28 @@ func (o *UpAppsOptions) Complete() error {
 }
 
 func (o *UpAppsOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism can't be negative")
+	}
+
 	return nil
 }
 
 func (o *UpAppsOptions) Run() error {
 	ui := o.UI
-	ui.Output("Deploying apps...", terminal.WithHeaderStyle())
-
-	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		var err error
-		if len(o.Config.AwsProfile) == 0 {
-			if v, exists := o.Config.Terraform["infra"]; exists {
-				o.Config.AwsProfile = v.AwsProfile
-			} else {
-				err = errors.New("can't detect aws_profile. Please set it via env var (AWS_PROFILE) or in ize.toml")
-			}
+	if len(o.Config.AwsProfile) == 0 {
+		v, exists := o.Config.Terraform["infra"]
+		if !exists {
+			return errors.New("can't detect aws_profile. Please set it via env var (AWS_PROFILE) or in ize.toml")
 		}
+		o.Config.AwsProfile = v.AwsProfile
+	}
 
-		err = deployApp(name, ui, o.Config, false)
-		if err != nil {
-			return err
-		}
+	ui.Output("Deploying apps...", terminal.WithHeaderStyle())
 
-		return nil
-	})
+	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
+		return deployApp(name, ui, o.Config, false)
+	}, manager.WithParallelism(o.Parallelism))
 	if err != nil {
 		return err
 	}
@@ -133,6 +137,11 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 	var m manager.Manager
 	var icon string
 
+	// apps brought up in parallel set AWS clients of their own profile and
+	// region on the project, so every app gets a copy
+	project := *cfg
+	cfg = &project
+
 	m = &ecs.Manager{
 		Project: cfg,
 		App:     &config.Ecs{Name: name},
@@ -144,6 +153,15 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 			Project: cfg,
 			App:     app,
 		}
+		icon = app.Icon
+	}
+	if app, ok := cfg.Helm[name]; ok {
+		app.Name = name
+		m = &helm.Manager{
+			Project: cfg,
+			App:     app,
+		}
+		icon = app.Icon
 	}
 	if app, ok := cfg.Alias[name]; ok {
 		app.Name = name
@@ -151,6 +169,7 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 			Project: cfg,
 			App:     app,
 		}
+		icon = app.Icon
 	}
 	if app, ok := cfg.Ecs[name]; ok {
 		app.Name = name
@@ -158,6 +177,7 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 			Project: cfg,
 			App:     app,
 		}
+		icon = app.Icon
 	}
 
 	if isExplain {
@@ -168,7 +188,13 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 		icon += " "
 	}
 
-	ui.Output("%s%s: bringing up...", icon, name, terminal.WithHeaderStyle())
+	// every app gets its own step group, so apps brought up in parallel
+	// don't mix up their progress
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	s := sg.Add("%s%s: bringing up...", icon, name)
+	defer func() { s.Abort() }()
 
 	// build app container
 	err := m.Build(ui)
@@ -188,7 +214,8 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 		return fmt.Errorf("can't deploy app: %w", err)
 	}
 
-	ui.Output("%s%s: done.\n", icon, name, terminal.WithSuccessStyle())
+	s.Update("%s%s: done", icon, name)
+	s.Done()
 
 	return nil
 }
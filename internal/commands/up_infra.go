@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/requirements"
@@ -21,23 +26,44 @@ import (
 )
 
 type UpInfraOptions struct {
-	Config     *config.Project
-	SkipGen    bool
-	AwsProfile string
-	AwsRegion  string
-	Version    string
-	UI         terminal.UI
-	Explain    bool
+	Config       *config.Project
+	SkipGen      bool
+	AwsProfile   string
+	AwsRegion    string
+	Version      string
+	UI           terminal.UI
+	Explain      bool
+	AutoApprove  bool
+	AllowDestroy bool
+	PlanOnly     bool
+	OutDir       string
+	ApplyPlan    string
 }
 
 var upInfraLongDesc = templates.LongDesc(`
 	Only deploy infrastructure.
+	The plan of every terraform stack is summarized and applied after confirmation, use --auto-approve to skip it.
+	Stacks without changes aren't applied.
+	Plans that destroy resources protected by prevent_destroy_types, max_destroys or protected_resources of the stack
+	are refused, use --allow-destroy to apply them anyway.
+
+	With --plan-only every stack is only planned and <out-dir>/<stack> gets the binary plan (tfplan),
+	its JSON representation (plan.json), a markdown summary (summary.md) and the version of the state
+	the plan is based on (state.json). --apply-plan applies these plans and refuses the ones whose
+	state changed since they were made.
 `)
 
 var upInfraExample = templates.Examples(`
 	# Deploy infra with flags
 	ize up infra --infra.terraform.version <version> --infra.terraform.aws-region <region> --infra.terraform.aws-profile <profile>
 
+	# Deploy infra without confirmation (e.g. in CI)
+	ize up infra --auto-approve
+
+	# Plan infra in CI and apply the reviewed plans in a later job
+	ize up infra --plan-only --out-dir plans
+	ize up infra --apply-plan plans
+
 	# Deploy infra with explicitly specified config file
 	ize --config-file /path/to/config up infra
 
@@ -83,6 +109,11 @@ func NewCmdUpInfra(project *config.Project) *cobra.Command {
 
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
+	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "apply terraform plans without confirmation")
+	cmd.Flags().BoolVar(&o.AllowDestroy, "allow-destroy", false, "apply terraform plans that violate the destroy policy of the stack")
+	cmd.Flags().BoolVar(&o.PlanOnly, "plan-only", false, "only plan terraform stacks and save the plans to --out-dir")
+	cmd.Flags().StringVar(&o.OutDir, "out-dir", "", "directory the plans of --plan-only are saved to")
+	cmd.Flags().StringVar(&o.ApplyPlan, "apply-plan", "", "apply the plans saved to the directory by --plan-only")
 	cmd.Flags().StringVar(&o.Version, "infra.terraform.version", "", "set terraform version")
 	cmd.Flags().StringVar(&o.AwsRegion, "infra.terraform.aws-region", "", "set aws region")
 	cmd.Flags().StringVar(&o.AwsProfile, "infra.terraform.aws-profile", "", "set aws profile")
@@ -125,7 +156,7 @@ func (o *UpInfraOptions) Complete() error {
 		}
 
 		if len(o.Config.Terraform["infra"].Version) == 0 {
-			o.Config.Terraform["infra"].Version = o.Config.TerraformVersion
+			o.Config.Terraform["infra"].Version = terraform.DefaultVersion(o.Config, "infra")
 		}
 	}
 
@@ -143,6 +174,18 @@ func (o *UpInfraOptions) Validate() error {
 		return fmt.Errorf("namespace must be specified")
 	}
 
+	if o.PlanOnly && len(o.OutDir) == 0 {
+		return fmt.Errorf("--out-dir must be specified with --plan-only")
+	}
+
+	if !o.PlanOnly && len(o.OutDir) != 0 {
+		return fmt.Errorf("--out-dir can be used only with --plan-only")
+	}
+
+	if o.PlanOnly && len(o.ApplyPlan) != 0 {
+		return fmt.Errorf("--plan-only and --apply-plan can't be used together")
+	}
+
 	return nil
 }
 
@@ -211,15 +254,26 @@ terraform apply
 
 	ui := o.UI
 
+	run := func(name string) error {
+		switch {
+		case o.PlanOnly:
+			return planInfra(name, ui, o.Config, o.SkipGen, o.OutDir)
+		case len(o.ApplyPlan) != 0:
+			return applyInfraPlan(name, ui, o.Config, o.SkipGen, o.ApplyPlan, o.AllowDestroy)
+		default:
+			return deployInfra(name, ui, o.Config, o.SkipGen, o.AutoApprove, o.AllowDestroy)
+		}
+	}
+
 	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := deployInfra("infra", ui, o.Config, o.SkipGen)
+		err := run("infra")
 		if err != nil {
 			return err
 		}
 	}
 
 	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		return deployInfra(name, ui, o.Config, o.SkipGen)
+		return run(name)
 	})
 	if err != nil {
 		return err
@@ -228,21 +282,176 @@ terraform apply
 	return nil
 }
 
-func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bool) error {
-	if !skipGen {
-		err := GenerateTerraformFiles(name, "", config)
+func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bool, autoApprove bool, allowDestroy bool) error {
+	logrus.Infof("infra: %v", config.Terraform[name])
+
+	tf, err := newTerraform(name, config, skipGen)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	ui.Output(fmt.Sprintf("[%s][%s] Running deploy infra...", config.Env, name), terminal.WithHeaderStyle())
+	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	err = selectWorkspace(ui, tf, config, name)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	ui.Output("Execution terraform plan...", terminal.WithHeaderStyle())
+
+	outPath := planPath(config, name)
+
+	//terraform plan run options
+	tf.NewCmd([]string{"plan", fmt.Sprintf("-out=%s", outPath)})
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	apply, err := reviewPlan(ui, tf, config, name, outPath, autoApprove, allowDestroy)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	if apply {
+		//terraform apply run options
+		tf.NewCmd([]string{"apply", "-auto-approve", outPath})
+
+		ui.Output("Execution terraform apply...", terminal.WithHeaderStyle())
+
+		err = tf.RunUI(ui)
 		if err != nil {
-			return err
+			return fmt.Errorf("can't deploy infra: %w", err)
 		}
 	}
 
-	var tf terraform.Terraform
+	err = storeOutputs(name, ui, tf, config)
+	if err != nil {
+		return fmt.Errorf("can't deploy infra: %w", err)
+	}
+
+	ui.Output("Deploy infra completed!\n", terminal.WithSuccessStyle())
+
+	return nil
+}
+
+// storeOutputs saves the outputs of the stack to SSM, so they can be read by
+// other stacks and ize output.
+func storeOutputs(name string, ui terminal.UI, tf terraform.Terraform, config *config.Project) error {
+	//terraform output run options
+	tf.NewCmd([]string{"output", "-json"})
+
+	var output bytes.Buffer
+
+	tf.SetOut(&output)
+	defer tf.SetOut(nil)
+
+	ui.Output("Execution terraform output...", terminal.WithHeaderStyle())
+
+	err := tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't get outputs of %s: %w", name, err)
+	}
+
+	byteValue, _ := ioutil.ReadAll(&output)
+	sDec := base64.StdEncoding.EncodeToString(byteValue)
+
+	// the outputs of infra are also kept under the legacy parameter, which
+	// is read by tunnel and start
+	parameterNames := []string{terraformOutputParameter(config.Env, name)}
+	if name == "infra" {
+		parameterNames = append(parameterNames, legacyTerraformOutputParameter(config.Env))
+	}
+
+	for _, parameterName := range parameterNames {
+		_, err = ssm.New(config.Session).PutParameter(&ssm.PutParameterInput{
+			Name:      aws.String(parameterName),
+			Value:     aws.String(sDec),
+			Type:      aws.String(ssm.ParameterTypeSecureString),
+			Overwrite: aws.Bool(true),
+			Tier:      aws.String(ssm.ParameterTierIntelligentTiering),
+			DataType:  aws.String("text"),
+		})
+		if err != nil {
+			return fmt.Errorf("can't store outputs of %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// stackOutput is an output of terraform output -json.
+type stackOutput struct {
+	Sensitive bool        `json:"sensitive"`
+	Type      interface{} `json:"type"`
+	Value     interface{} `json:"value"`
+}
 
-	logrus.Infof("infra: %s", config.Terraform[name])
+// terraformOutputParameter returns the SSM parameter the outputs of the stack
+// are stored in.
+func terraformOutputParameter(env string, stack string) string {
+	return fmt.Sprintf("/%s/terraform-output/%s", env, stack)
+}
+
+func legacyTerraformOutputParameter(env string) string {
+	return fmt.Sprintf("/%s/terraform-output", env)
+}
+
+// getTerraformOutputs returns the outputs of the stack stored in SSM by
+// deployInfra. The outputs of infra deployed by older versions of ize are
+// read from the legacy parameter.
+func getTerraformOutputs(svc ssmiface.SSMAPI, env string, stack string) (map[string]stackOutput, error) {
+	resp, err := svc.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(terraformOutputParameter(env, stack)),
+		WithDecryption: aws.Bool(true),
+	})
+
+	var aerr awserr.Error
+	if stack == "infra" && errors.As(err, &aerr) && aerr.Code() == ssm.ErrCodeParameterNotFound {
+		resp, err = svc.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(legacyTerraformOutputParameter(env)),
+			WithDecryption: aws.Bool(true),
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get outputs of %s: %w", stack, err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(*resp.Parameter.Value)
+	if err != nil {
+		return nil, fmt.Errorf("can't get outputs of %s: %w", stack, err)
+	}
+
+	var outputs map[string]stackOutput
+
+	err = json.Unmarshal(value, &outputs)
+	if err != nil {
+		return nil, fmt.Errorf("can't get outputs of %s: %w", stack, err)
+	}
+
+	return outputs, nil
+}
+
+// newTerraform generates the terraform files of the stack unless skipGen is
+// set and returns the runner of the preferred runtime set up to run init.
+func newTerraform(name string, config *config.Project, skipGen bool) (terraform.Terraform, error) {
+	if !skipGen {
+		err := GenerateTerraformFiles(name, "", config)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	v, err := config.Session.Config.Credentials.Get()
 	if err != nil {
-		return fmt.Errorf("can't get AWS credentials: %w", err)
+		return nil, fmt.Errorf("can't get AWS credentials: %w", err)
 	}
 
 	env := []string{
@@ -255,6 +464,8 @@ func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bo
 		fmt.Sprintf("AWS_SESSION_TOKEN=%v", v.SessionToken),
 	}
 
+	var tf terraform.Terraform
+
 	switch config.PreferRuntime {
 	case "docker":
 		tf = terraform.NewDockerTerraform(name, []string{"init", "-input=true"}, env, nil, config)
@@ -262,81 +473,123 @@ func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bo
 		tf = terraform.NewLocalTerraform(name, []string{"init", "-input=true"}, env, nil, config)
 		err = tf.Prepare()
 		if err != nil {
-			return fmt.Errorf("can't deploy infra: %w", err)
+			return nil, err
 		}
 	default:
-		return fmt.Errorf("can't supported %s runtime", config.PreferRuntime)
+		return nil, fmt.Errorf("can't supported %s runtime", config.PreferRuntime)
 	}
 
-	ui.Output(fmt.Sprintf("[%s][%s] Running deploy infra...", config.Env, name), terminal.WithHeaderStyle())
-	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
+	return tf, nil
+}
 
-	err = tf.RunUI(ui)
-	if err != nil {
-		return fmt.Errorf("can't deploy infra: %w", err)
+// selectWorkspace selects the workspace named after the env for stacks with
+// the workspace layout. The workspace is created if it doesn't exist yet.
+func selectWorkspace(ui terminal.UI, tf terraform.Terraform, config *config.Project, name string) error {
+	if !config.Terraform[name].IsWorkspaceLayout() {
+		return nil
 	}
 
-	ui.Output("Execution terraform plan...", terminal.WithHeaderStyle())
+	ui.Output("Execution terraform workspace select...", terminal.WithHeaderStyle())
 
-	outPath := filepath.Join(config.EnvDir, name, ".terraform", "tfplan")
-	if name == "infra" {
-		outPath = filepath.Join(config.EnvDir, ".terraform", "tfplan")
-	}
+	var out bytes.Buffer
 
-	//terraform plan run options
-	tf.NewCmd([]string{"plan", fmt.Sprintf("-out=%s", outPath)})
+	tf.NewCmd([]string{"workspace", "select", config.Env})
+	tf.SetOut(&out)
+	err := tf.RunUI(ui)
+	tf.SetOut(nil)
 
-	err = tf.RunUI(ui)
+	var exitErr *terraform.ExitError
+	if errors.As(err, &exitErr) && workspaceNotExist(out.String()) {
+		tf.NewCmd([]string{"workspace", "new", config.Env})
+		err = tf.RunUI(ui)
+	} else if err != nil {
+		// backend and credentials errors must not create a workspace
+		ui.Output("%s", strings.TrimSpace(out.String()), terminal.WithErrorStyle())
+	}
 	if err != nil {
-		return fmt.Errorf("can't deploy infra: %w", err)
+		return fmt.Errorf("can't select workspace %s: %w", config.Env, err)
 	}
 
-	//terraform apply run options
-	tf.NewCmd([]string{"apply", "-auto-approve", outPath})
+	return nil
+}
 
-	ui.Output("Execution terraform apply...", terminal.WithHeaderStyle())
+// workspaceNotExist reports whether the output of terraform workspace select
+// says that the workspace doesn't exist.
+func workspaceNotExist(output string) bool {
+	return strings.Contains(output, "doesn't exist")
+}
 
-	err = tf.RunUI(ui)
+// planPath returns the path of the plan file of the stack.
+func planPath(config *config.Project, name string) string {
+	return filepath.Join(config.StackDir(name), ".terraform", "tfplan")
+}
+
+// reviewPlan prints the summary of the plan and asks whether it should be
+// applied. Plans without changes are never applied, plans that violate the
+// destroy policy of the stack are applied only if allowDestroy is set.
+func reviewPlan(ui terminal.UI, tf terraform.Terraform, config *config.Project, name string, path string, autoApprove bool, allowDestroy bool) (bool, error) {
+	var out bytes.Buffer
+
+	tf.NewCmd([]string{"show", "-json", path})
+	tf.SetOut(&out)
+	defer tf.SetOut(nil)
+
+	err := tf.RunUI(ui)
 	if err != nil {
-		return fmt.Errorf("can't deploy infra: %w", err)
+		return false, fmt.Errorf("can't show plan: %w", err)
 	}
 
-	//terraform output run options
-
-	tf.NewCmd([]string{"output", "-json"})
+	plan, err := terraform.ParsePlan(out.Bytes())
+	if err != nil {
+		return false, err
+	}
 
-	var output bytes.Buffer
+	summary := plan.Summary()
+	if !summary.HasChanges() {
+		ui.Output("[%s] No changes, skipping apply", name, terminal.WithSuccessStyle())
+		return false, nil
+	}
 
-	tf.SetOut(&output)
+	ui.Output("[%s] Terraform will perform the following actions:\n%s", name, summary.String())
 
-	ui.Output("Execution terraform output...", terminal.WithHeaderStyle())
+	if violations := summary.Violations(destroyPolicy(config.Terraform[name])); len(violations) != 0 {
+		if !allowDestroy {
+			return false, fmt.Errorf("plan of %s violates the destroy policy, use --allow-destroy to apply it anyway:\n  - %s", name, strings.Join(violations, "\n  - "))
+		}
 
-	err = tf.RunUI(ui)
-	if err != nil {
-		return fmt.Errorf("can't deploy infra: %w", err)
+		ui.Output("[%s] Destroy policy violations are allowed by --allow-destroy:\n  - %s", name, strings.Join(violations, "\n  - "), terminal.WithWarningStyle())
 	}
 
-	parameterName := fmt.Sprintf("/%s/terraform-output", config.Env)
-
-	byteValue, _ := ioutil.ReadAll(&output)
-	sDec := base64.StdEncoding.EncodeToString(byteValue)
-	if err != nil {
-		return err
+	if autoApprove {
+		return true, nil
 	}
 
-	_, err = ssm.New(config.Session).PutParameter(&ssm.PutParameterInput{
-		Name:      &parameterName,
-		Value:     aws.String(sDec),
-		Type:      aws.String(ssm.ParameterTypeSecureString),
-		Overwrite: aws.Bool(true),
-		Tier:      aws.String(ssm.ParameterTierIntelligentTiering),
-		DataType:  aws.String("text"),
+	answer, err := ui.Input(&terminal.Input{
+		Prompt: fmt.Sprintf("Do you want to apply the plan of %s? Only 'yes' will be accepted:", name),
+		Style:  terminal.WarningStyle,
 	})
 	if err != nil {
-		return err
+		if errors.Is(err, terminal.ErrNonInteractive) {
+			return false, fmt.Errorf("can't ask for confirmation, use --auto-approve to apply the plan: %w", err)
+		}
+		return false, fmt.Errorf("can't ask for confirmation: %w", err)
 	}
 
-	ui.Output("Deploy infra completed!\n", terminal.WithSuccessStyle())
+	if answer != "yes" {
+		return false, fmt.Errorf("apply of %s cancelled", name)
+	}
 
-	return nil
+	return true, nil
+}
+
+func destroyPolicy(stack *config.Terraform) terraform.Policy {
+	if stack == nil {
+		return terraform.Policy{}
+	}
+
+	return terraform.Policy{
+		PreventDestroyTypes: stack.PreventDestroyTypes,
+		MaxDestroys:         stack.MaxDestroys,
+		ProtectedResources:  stack.ProtectedResources,
+	}
 }
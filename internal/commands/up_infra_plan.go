@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/terraform"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+// Files of the plan artifacts written to <out-dir>/<stack> by --plan-only.
+const (
+	planArtifactPlan    = "tfplan"
+	planArtifactJSON    = "plan.json"
+	planArtifactSummary = "summary.md"
+	planArtifactState   = "state.json"
+)
+
+// planInfra plans the stack and saves the binary plan, its JSON
+// representation, a markdown summary and the version of the state the plan
+// is based on to <outDir>/<name>.
+func planInfra(name string, ui terminal.UI, config *config.Project, skipGen bool, outDir string) error {
+	tf, err := newTerraform(name, config, skipGen)
+	if err != nil {
+		return fmt.Errorf("can't plan infra: %w", err)
+	}
+
+	ui.Output(fmt.Sprintf("[%s][%s] Running plan infra...", config.Env, name), terminal.WithHeaderStyle())
+	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't plan infra: %w", err)
+	}
+
+	err = selectWorkspace(ui, tf, config, name)
+	if err != nil {
+		return fmt.Errorf("can't plan infra: %w", err)
+	}
+
+	state, err := pullStateVersion(ui, tf)
+	if err != nil {
+		return fmt.Errorf("can't plan infra: %w", err)
+	}
+
+	ui.Output("Execution terraform plan...", terminal.WithHeaderStyle())
+
+	// the plan is written to the stack first, because the docker runner can
+	// only write to the mounted dirs
+	outPath := planPath(config, name)
+
+	tf.NewCmd([]string{"plan", "-input=false", fmt.Sprintf("-out=%s", outPath)})
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't plan infra: %w", err)
+	}
+
+	var out bytes.Buffer
+
+	tf.NewCmd([]string{"show", "-json", outPath})
+	tf.SetOut(&out)
+	defer tf.SetOut(nil)
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't show plan: %w", err)
+	}
+
+	planJSON := out.Bytes()
+	if i := bytes.IndexByte(planJSON, '{'); i > 0 {
+		planJSON = planJSON[i:]
+	}
+
+	plan, err := terraform.ParsePlan(planJSON)
+	if err != nil {
+		return err
+	}
+
+	summary := plan.Summary()
+	violations := summary.Violations(destroyPolicy(config.Terraform[name]))
+
+	dir := filepath.Join(outDir, name)
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+
+	binaryPlan, err := os.ReadFile(outPath)
+	if err != nil {
+		return fmt.Errorf("can't read plan: %w", err)
+	}
+
+	stateJSON, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal state version: %w", err)
+	}
+
+	artifacts := map[string][]byte{
+		planArtifactPlan:    binaryPlan,
+		planArtifactJSON:    planJSON,
+		planArtifactSummary: []byte(planMarkdown(config.Env, name, summary, violations)),
+		planArtifactState:   append(stateJSON, '\n'),
+	}
+
+	for file, data := range artifacts {
+		err = os.WriteFile(filepath.Join(dir, file), data, 0644)
+		if err != nil {
+			return fmt.Errorf("can't write %s: %w", file, err)
+		}
+	}
+
+	ui.Output("[%s] Terraform will perform the following actions:\n%s", name, summary.String())
+	ui.Output("[%s] Plan saved to %s\n", name, dir, terminal.WithSuccessStyle())
+
+	return nil
+}
+
+// applyInfraPlan applies the plan saved by planInfra to <planDir>/<name>. The
+// plan is refused when the state was changed since the plan was made.
+func applyInfraPlan(name string, ui terminal.UI, config *config.Project, skipGen bool, planDir string, allowDestroy bool) error {
+	dir := filepath.Join(planDir, name)
+
+	b, err := os.ReadFile(filepath.Join(dir, planArtifactState))
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	var planned terraform.StateVersion
+
+	err = json.Unmarshal(b, &planned)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: can't parse %s: %w", name, planArtifactState, err)
+	}
+
+	binaryPlan, err := os.ReadFile(filepath.Join(dir, planArtifactPlan))
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	tf, err := newTerraform(name, config, skipGen)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	ui.Output(fmt.Sprintf("[%s][%s] Running apply plan...", config.Env, name), terminal.WithHeaderStyle())
+	ui.Output("Execution terraform init...", terminal.WithHeaderStyle())
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	err = selectWorkspace(ui, tf, config, name)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	current, err := pullStateVersion(ui, tf)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	if current != planned {
+		return fmt.Errorf("can't apply plan of %s: the state changed since the plan was made (serial %d, lineage %s; planned at serial %d, lineage %s), plan again", name, current.Serial, current.Lineage, planned.Serial, planned.Lineage)
+	}
+
+	outPath := planPath(config, name)
+
+	err = os.WriteFile(outPath, binaryPlan, 0644)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	// the plan was reviewed when it was made, only the destroy policy is
+	// checked again
+	apply, err := reviewPlan(ui, tf, config, name, outPath, true, allowDestroy)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	if apply {
+		tf.NewCmd([]string{"apply", "-input=false", outPath})
+
+		ui.Output("Execution terraform apply...", terminal.WithHeaderStyle())
+
+		err = tf.RunUI(ui)
+		if err != nil {
+			return fmt.Errorf("can't apply plan of %s: %w", name, err)
+		}
+	}
+
+	err = storeOutputs(name, ui, tf, config)
+	if err != nil {
+		return fmt.Errorf("can't apply plan of %s: %w", name, err)
+	}
+
+	ui.Output("Apply plan completed!\n", terminal.WithSuccessStyle())
+
+	return nil
+}
+
+// pullStateVersion returns the version of the current state of the stack.
+func pullStateVersion(ui terminal.UI, tf terraform.Terraform) (terraform.StateVersion, error) {
+	var out bytes.Buffer
+
+	tf.NewCmd([]string{"state", "pull"})
+	tf.SetOut(&out)
+	defer tf.SetOut(nil)
+
+	err := tf.RunUI(ui)
+	if err != nil {
+		return terraform.StateVersion{}, fmt.Errorf("can't pull state: %w", err)
+	}
+
+	return terraform.ParseStateVersion(out.Bytes())
+}
+
+func planMarkdown(env string, name string, summary terraform.PlanSummary, violations []string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "### Terraform plan of `%s` (%s)\n\n", name, env)
+
+	if !summary.HasChanges() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+
+	b.WriteString(summary.Markdown())
+
+	if len(violations) != 0 {
+		b.WriteString("\n**Destroy policy violations** (the plan is applied only with `--allow-destroy`):\n\n")
+		for _, v := range violations {
+			fmt.Fprintf(&b, "- %s\n", v)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/hazelops/ize/internal/terraform"
+)
+
+func Test_planMarkdown(t *testing.T) {
+	plan, err := terraform.ParsePlan([]byte(`{"format_version":"1.1","resource_changes":[
+		{"address":"aws_db_instance.main","change":{"actions":["delete","create"]}},
+		{"address":"aws_vpc.main","change":{"actions":["no-op"]}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		summary    terraform.PlanSummary
+		violations []string
+		want       string
+	}{
+		{
+			name: "no changes",
+			want: "### Terraform plan of `rds` (dev)\n\nNo changes.\n",
+		},
+		{
+			name:       "changes",
+			summary:    plan.Summary(),
+			violations: []string{"aws_db_instance.main would be replaced, destroying aws_db_instance resources is prevented"},
+			want: "### Terraform plan of `rds` (dev)\n\n" +
+				"**Plan:** 1 to add, 0 to change, 1 to destroy.\n\n" +
+				"| Action | Resource |\n|--------|----------|\n" +
+				"| `-/+` replace | `aws_db_instance.main` |\n\n" +
+				"**Destroy policy violations** (the plan is applied only with `--allow-destroy`):\n\n" +
+				"- aws_db_instance.main would be replaced, destroying aws_db_instance resources is prevented\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planMarkdown("dev", "rds", tt.summary, tt.violations); got != tt.want {
+				t.Errorf("planMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package commands
+
+import "testing"
+
+func Test_workspaceNotExist(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{
+			name:   "missing workspace",
+			output: "\nWorkspace \"dev\" doesn't exist.\n\nYou can create this workspace with the \"new\" subcommand.\n",
+			want:   true,
+		},
+		{
+			name:   "backend error",
+			output: "\nError: error loading state: AccessDenied: Access Denied\n\tstatus code: 403\n",
+			want:   false,
+		},
+		{
+			name:   "expired credentials",
+			output: "\nError: error configuring S3 Backend: ExpiredToken: The security token included in the request is expired\n",
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workspaceNotExist(tt.output); got != tt.want {
+				t.Errorf("workspaceNotExist() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/terraform"
+)
+
+func Test_setTerraformDefaults(t *testing.T) {
+	project := &config.Project{
+		TerraformVersion: "1.1.3",
+		Terraform: map[string]*config.Terraform{
+			"infra": {},
+			"dns":   {Engine: terraform.EngineOpenTofu},
+			"vpc":   {Engine: terraform.EngineOpenTofu, Version: "1.7.0"},
+		},
+	}
+
+	setTerraformDefaults(project)
+
+	want := map[string]string{
+		"infra": "1.1.3",
+		"dns":   terraform.DefaultTofuVersion,
+		"vpc":   "1.7.0",
+	}
+
+	for name, version := range want {
+		if got := project.Terraform[name].Version; got != version {
+			t.Errorf("version of %s = %s, want %s", name, got, version)
+		}
+	}
+}
+
+func TestUpOptions_validate(t *testing.T) {
+	o := &UpOptions{
+		Config:      &config.Project{Env: "test", Namespace: "testnut"},
+		AppName:     "squibby",
+		Parallelism: -1,
+	}
+
+	if err := o.validate(); err == nil {
+		t.Errorf("validate() accepted a negative parallelism")
+	}
+
+	o.Parallelism = 2
+	if err := o.validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+}
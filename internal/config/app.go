@@ -15,6 +15,16 @@ type Ecs struct {
 	AwsRegion              string   `mapstructure:"aws_region,omitempty"`
 	DependsOn              []string `mapstructure:"depends_on,omitempty"`
 	ServiceName            string   `mapstructure:"service_name,omitempty"`
+	Containers             []string `mapstructure:"containers,omitempty"`
+	ContainerImages        []string `mapstructure:"container_images,omitempty"`
+	DesiredCount           *int64   `mapstructure:"desired_count,omitempty"`
+	Cpu                    string   `mapstructure:"cpu,omitempty"`
+	Memory                 string   `mapstructure:"memory,omitempty"`
+	Environment            []string `mapstructure:"environment,omitempty"`
+	CodeDeployApplication  string   `mapstructure:"codedeploy_application,omitempty"`
+	CodeDeployGroup        string   `mapstructure:"codedeploy_deployment_group,omitempty"`
+	DeploymentConfig       string   `mapstructure:"deployment_config,omitempty"`
+	SecretsFrom            string   `mapstructure:"secrets_from,omitempty"`
 }
 
 type Helm struct {
@@ -23,6 +33,11 @@ type Helm struct {
 	Image          string   `mapstructure:",omitempty"`
 	Namespace      string   `mapstructure:",omitempty"`
 	HelmRelease    string   `mapstructure:"helm_release,omitempty"`
+	ChartPath      string   `mapstructure:"chart_path,omitempty"`
+	ValuesFiles    []string `mapstructure:"values_files,omitempty"`
+	Set            []string `mapstructure:"set,omitempty"`
+	KubeContext    string   `mapstructure:"kube_context,omitempty"`
+	Kubeconfig     string   `mapstructure:"kubeconfig,omitempty"`
 	DockerRegistry string   `mapstructure:"docker_registry,omitempty"`
 	Timeout        int      `mapstructure:",omitempty"`
 	SkipDeploy     bool     `mapstructure:"skip_deploy,omitempty"`
@@ -48,6 +63,8 @@ type Serverless struct {
 	AwsProfile              string   `mapstructure:"aws_profile,omitempty"`
 	AwsRegion               string   `mapstructure:"aws_region,omitempty"`
 	DependsOn               []string `mapstructure:"depends_on,omitempty"`
+	// RollbackTimestamp is the timestamp of the deployment Redeploy rolls back to
+	RollbackTimestamp string `mapstructure:"-"`
 }
 
 type Alias struct {
@@ -71,11 +71,21 @@ func (p *Project) GetConfig() error {
 		return err
 	}
 
+	err = p.restoreTerraformVarNames(viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+
 	err = findDuplicates(p)
 	if err != nil {
 		return err
 	}
 
+	err = findDependencyErrors(p)
+	if err != nil {
+		return err
+	}
+
 	if p.LocalStack {
 		// Set default Endpoint URL for localstack if it's enabled
 		if len(p.EndpointUrl) == 0 {
@@ -189,6 +199,11 @@ func (p *Project) GetTestConfig() error {
 		return err
 	}
 
+	err = p.restoreTerraformVarNames(viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+
 	sess, err := utils.GetTestSession(&utils.SessionConfig{
 		Region:      p.AwsRegion,
 		Profile:     p.AwsProfile,
@@ -390,6 +405,20 @@ func findDuplicates(cfg *Project) error {
 		existingKeys[k] = "serverless"
 	}
 
+	for k := range cfg.Helm {
+		if val, ok := existingKeys[k]; ok {
+			if duplicateKeys[k] == nil {
+				duplicateKeys[k] = map[string]string{}
+			}
+			duplicateKeys[k]["helm"] = k
+			if _, ok := duplicateKeys[k][val]; !ok {
+				duplicateKeys[k][val] = k
+
+			}
+		}
+		existingKeys[k] = "helm"
+	}
+
 	for k := range cfg.Alias {
 		if val, ok := existingKeys[k]; ok {
 			if duplicateKeys[k] == nil {
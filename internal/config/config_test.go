@@ -8,7 +8,11 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/hazelops/ize/internal/schema"
 )
 
 func TestSetTag(t *testing.T) {
@@ -62,3 +66,124 @@ func TestSetTag(t *testing.T) {
 		})
 	}
 }
+
+func TestProject_StackDir(t *testing.T) {
+	p := &Project{
+		RootDir:  "/home/testnut/example",
+		InfraDir: "/home/testnut/example/.ize",
+		EnvDir:   "/home/testnut/example/.ize/env/dev",
+		Terraform: map[string]*Terraform{
+			"infra":    {},
+			"vpc":      {},
+			"rds":      {Layout: LayoutWorkspace},
+			"cdn":      {Layout: LayoutWorkspace, Path: "terraform/cdn"},
+			"external": {Path: "/opt/stacks/external"},
+		},
+	}
+
+	tests := map[string]string{
+		"infra":    "/home/testnut/example/.ize/env/dev",
+		"vpc":      "/home/testnut/example/.ize/env/dev/vpc",
+		"rds":      "/home/testnut/example/.ize/terraform/rds",
+		"cdn":      "/home/testnut/example/terraform/cdn",
+		"external": "/opt/stacks/external",
+	}
+	for name, want := range tests {
+		if got := p.StackDir(name); got != want {
+			t.Errorf("StackDir(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestProject_restoreTerraformVarNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ize.toml")
+	err := os.WriteFile(path, []byte(`
+[terraform.Infra]
+vars = { vpcCIDR = "10.0.0.0/16", azCount = 2 }
+inputs = { dbHost = "rds.endpoint" }
+
+[terraform.rds]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Project{}
+	if err := v.Unmarshal(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.restoreTerraformVarNames(path); err != nil {
+		t.Fatal(err)
+	}
+
+	infra := p.Terraform["infra"]
+	if got := infra.Vars["vpcCIDR"]; got != "10.0.0.0/16" {
+		t.Errorf("vars.vpcCIDR = %v, want 10.0.0.0/16 (vars: %v)", got, infra.Vars)
+	}
+	if got := infra.Vars["azCount"]; got != int64(2) {
+		t.Errorf("vars.azCount = %v, want 2 (vars: %v)", got, infra.Vars)
+	}
+	if _, ok := infra.Vars["vpccidr"]; ok {
+		t.Errorf("lowercased key is still set: %v", infra.Vars)
+	}
+	if got := infra.Inputs["dbHost"]; got != "rds.endpoint" {
+		t.Errorf("inputs.dbHost = %v, want rds.endpoint (inputs: %v)", got, infra.Inputs)
+	}
+	if rds := p.Terraform["rds"]; rds != nil && rds.Vars != nil {
+		t.Errorf("rds.vars = %v, want none", rds.Vars)
+	}
+}
+
+func TestProject_ecsCpuMemory(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	err := v.ReadConfig(strings.NewReader(`
+env = "dev"
+namespace = "testnut"
+aws_profile = "default"
+aws_region = "us-east-1"
+prefer_runtime = "native"
+home = "/home/testnut"
+root_dir = "/home/testnut/example"
+ize_dir = "/home/testnut/example/.ize"
+env_dir = "/home/testnut/example/.ize/env/dev"
+
+[ecs.web]
+cpu = 256
+memory = 512
+
+[ecs.api]
+cpu = "1 vCPU"
+memory = "2 GB"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := schema.Validate(v.AllSettings()); err != nil {
+		t.Fatalf("schema.Validate() error = %v", err)
+	}
+
+	p := &Project{}
+	if err := v.Unmarshal(p); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	tests := map[string][2]string{
+		"web": {"256", "512"},
+		"api": {"1 vCPU", "2 GB"},
+	}
+	for name, want := range tests {
+		app := p.Ecs[name]
+		if app.Cpu != want[0] || app.Memory != want[1] {
+			t.Errorf("ecs.%s cpu, memory = %q, %q, want %q, %q", name, app.Cpu, app.Memory, want[0], want[1])
+		}
+	}
+}
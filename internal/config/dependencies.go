@@ -0,0 +1,313 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type dependencyNode struct {
+	kind      string
+	dependsOn []string
+}
+
+// GetAll returns terraform stacks and apps of all kinds in the same format
+// as GetApps, so they can be scheduled as a single dependency graph.
+//
+// Besides the depends_on entries, the graph keeps the order ize has always
+// used: terraform stacks wait for the infra stack, and apps that don't depend
+// on any terraform stack wait for all of them.
+func (p *Project) GetAll() map[string]*interface{} {
+	nodes := p.dependencyNodes()
+
+	deps := map[string][]string{}
+	for name, node := range nodes {
+		deps[name] = append([]string{}, node.dependsOn...)
+	}
+
+	// edges are added one by one and only when they don't introduce a cycle
+	addEdge := func(from, to string) {
+		if from == to || stringContains(deps[from], to) || reaches(deps, to, from) {
+			return
+		}
+		deps[from] = append(deps[from], to)
+	}
+
+	var stacks []string
+	for _, name := range sortedNodeNames(nodes) {
+		if nodes[name].kind == "terraform" {
+			stacks = append(stacks, name)
+		}
+	}
+
+	if _, ok := p.Terraform["infra"]; ok {
+		for _, name := range stacks {
+			addEdge(name, "infra")
+		}
+	}
+
+	for _, name := range sortedNodeNames(nodes) {
+		if nodes[name].kind == "terraform" || dependsOnStack(nodes, name) {
+			continue
+		}
+		for _, stack := range stacks {
+			addEdge(name, stack)
+		}
+	}
+
+	return toGraphNodes(deps)
+}
+
+// GetDependencies returns the terraform stack or app with the given name and
+// everything it transitively depends on through depends_on.
+func (p *Project) GetDependencies(name string) map[string]*interface{} {
+	nodes := p.dependencyNodes()
+	deps := map[string][]string{}
+
+	var walk func(name string)
+	walk = func(name string) {
+		if _, ok := deps[name]; ok {
+			return
+		}
+
+		deps[name] = nodes[name].dependsOn
+		for _, d := range nodes[name].dependsOn {
+			if _, ok := nodes[d]; ok {
+				walk(d)
+			}
+		}
+	}
+	walk(name)
+
+	return toGraphNodes(deps)
+}
+
+func toGraphNodes(deps map[string][]string) map[string]*interface{} {
+	all := map[string]*interface{}{}
+
+	for name, d := range deps {
+		var v interface{}
+		v = map[string]interface{}{
+			"depends_on": d,
+		}
+		all[name] = &v
+	}
+
+	return all
+}
+
+func dependsOnStack(nodes map[string]dependencyNode, name string) bool {
+	for _, d := range nodes[name].dependsOn {
+		if nodes[d].kind == "terraform" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reaches reports whether there is a path from one node to another
+func reaches(deps map[string][]string, from, to string) bool {
+	seen := map[string]bool{}
+
+	var walk func(name string) bool
+	walk = func(name string) bool {
+		if name == to {
+			return true
+		}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+
+		for _, d := range deps[name] {
+			if walk(d) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return walk(from)
+}
+
+func stringContains(array []string, needle string) bool {
+	for _, val := range array {
+		if val == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetKind returns the kind of section (terraform, ecs, serverless, helm or
+// alias) the name is declared in. It returns an empty string for unknown names.
+func (p *Project) GetKind(name string) string {
+	return p.dependencyNodes()[name].kind
+}
+
+func (p *Project) dependencyNodes() map[string]dependencyNode {
+	nodes := map[string]dependencyNode{}
+
+	for name, body := range p.Terraform {
+		nodes[name] = dependencyNode{kind: "terraform", dependsOn: body.Dependencies()}
+	}
+
+	for name, body := range p.Ecs {
+		nodes[name] = dependencyNode{kind: "ecs", dependsOn: body.DependsOn}
+	}
+
+	for name, body := range p.Serverless {
+		nodes[name] = dependencyNode{kind: "serverless", dependsOn: body.DependsOn}
+	}
+
+	for name, body := range p.Helm {
+		nodes[name] = dependencyNode{kind: "helm", dependsOn: body.DependsOn}
+	}
+
+	for name, body := range p.Alias {
+		nodes[name] = dependencyNode{kind: "alias", dependsOn: body.DependsOn}
+	}
+
+	return nodes
+}
+
+// duplicateNodeNames returns the names that are declared in more than one
+// section as "[kind.name] and [kind.name]", sorted. The graph has a single node
+// per name, so one of them would be lost.
+func (p *Project) duplicateNodeNames() []string {
+	kinds := map[string][]string{}
+
+	add := func(kind string, name string) {
+		kinds[name] = append(kinds[name], fmt.Sprintf("[%s.%s]", kind, name))
+	}
+
+	for name := range p.Terraform {
+		add("terraform", name)
+	}
+	for name := range p.Ecs {
+		add("ecs", name)
+	}
+	for name := range p.Serverless {
+		add("serverless", name)
+	}
+	for name := range p.Helm {
+		add("helm", name)
+	}
+	for name := range p.Alias {
+		add("alias", name)
+	}
+
+	var duplicates []string
+	for _, k := range kinds {
+		if len(k) > 1 {
+			duplicates = append(duplicates, strings.Join(k, " and "))
+		}
+	}
+	sort.Strings(duplicates)
+
+	return duplicates
+}
+
+// findDependencyErrors checks that names of terraform stacks and apps are
+// unique, that every depends_on entry refers to an existing terraform stack or
+// app and that the dependencies don't form a cycle.
+func findDependencyErrors(cfg *Project) error {
+	if duplicates := cfg.duplicateNodeNames(); len(duplicates) != 0 {
+		return fmt.Errorf("names of terraform stacks and apps must be unique:\n%s have the same name", strings.Join(duplicates, " have the same name\n"))
+	}
+
+	nodes := cfg.dependencyNodes()
+
+	errMsg := ""
+	for _, name := range sortedNodeNames(nodes) {
+		for _, d := range nodes[name].dependsOn {
+			if _, ok := nodes[d]; !ok {
+				errMsg += fmt.Sprintf("\n[%s.%s] depends on \"%s\", but there is no terraform stack or app with this name", nodes[name].kind, name, d)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(nodes); len(cycle) != 0 {
+		path := make([]string, len(cycle))
+		for i, name := range cycle {
+			path[i] = fmt.Sprintf("[%s.%s]", nodes[name].kind, name)
+		}
+		errMsg += fmt.Sprintf("\ndependency cycle found: %s", strings.Join(path, " -> "))
+	}
+
+	if len(errMsg) != 0 {
+		return fmt.Errorf("invalid depends_on:%s", errMsg)
+	}
+
+	return nil
+}
+
+// findDependencyCycle returns the first cycle found as a path that starts and
+// ends with the same name. Names are visited in sorted order, so the result
+// is stable.
+func findDependencyCycle(nodes map[string]dependencyNode) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		deps := append([]string{}, nodes[name].dependsOn...)
+		sort.Strings(deps)
+
+		for _, d := range deps {
+			if _, ok := nodes[d]; !ok {
+				continue
+			}
+
+			switch state[d] {
+			case visiting:
+				for i, n := range path {
+					if n == d {
+						return append(append([]string{}, path[i:]...), d)
+					}
+				}
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range sortedNodeNames(nodes) {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedNodeNames(nodes map[string]dependencyNode) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_findDependencyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		project *Project
+		wantErr string
+	}{
+		{
+			name: "valid",
+			project: &Project{
+				Terraform: map[string]*Terraform{
+					"infra": {},
+					"rds":   {DependsOn: []string{"infra"}},
+				},
+				Ecs: map[string]*Ecs{
+					"api": {DependsOn: []string{"rds"}},
+				},
+				Helm: map[string]*Helm{
+					"web": {DependsOn: []string{"api"}},
+				},
+				Alias: map[string]*Alias{
+					"all": {DependsOn: []string{"web", "api"}},
+				},
+			},
+		},
+		{
+			name: "dangling reference",
+			project: &Project{
+				Ecs: map[string]*Ecs{
+					"api": {DependsOn: []string{"rsd"}},
+				},
+			},
+			wantErr: `[ecs.api] depends on "rsd", but there is no terraform stack or app with this name`,
+		},
+		{
+			name: "self reference",
+			project: &Project{
+				Serverless: map[string]*Serverless{
+					"lambda": {DependsOn: []string{"lambda"}},
+				},
+			},
+			wantErr: "dependency cycle found: [serverless.lambda] -> [serverless.lambda]",
+		},
+		{
+			name: "cycle across kinds",
+			project: &Project{
+				Terraform: map[string]*Terraform{
+					"rds": {DependsOn: []string{"worker"}},
+				},
+				Ecs: map[string]*Ecs{
+					"api":    {DependsOn: []string{"rds"}},
+					"worker": {DependsOn: []string{"api"}},
+				},
+			},
+			wantErr: "dependency cycle found: [ecs.api] -> [terraform.rds] -> [ecs.worker] -> [ecs.api]",
+		},
+		{
+			name: "duplicate names across kinds",
+			project: &Project{
+				Terraform: map[string]*Terraform{
+					"api": {},
+				},
+				Ecs: map[string]*Ecs{
+					"api": {},
+					"web": {DependsOn: []string{"api"}},
+				},
+				Helm: map[string]*Helm{
+					"web": {},
+				},
+			},
+			wantErr: "[ecs.web] and [helm.web] have the same name\n[terraform.api] and [ecs.api] have the same name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := findDependencyErrors(tt.project)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("findDependencyErrors() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("findDependencyErrors() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProject_GetAll(t *testing.T) {
+	p := &Project{
+		Terraform: map[string]*Terraform{
+			"infra": {},
+			"rds":   {},
+			"cdn":   {},
+		},
+		Ecs: map[string]*Ecs{
+			"api":    {DependsOn: []string{"rds"}},
+			"worker": {},
+		},
+	}
+
+	want := map[string][]string{
+		"infra":  {},
+		"rds":    {"infra"},
+		"cdn":    {"infra"},
+		"api":    {"rds"},
+		"worker": {"cdn", "infra", "rds"},
+	}
+
+	got := p.GetAll()
+	if len(got) != len(want) {
+		t.Fatalf("GetAll() returned %d nodes, want %d", len(got), len(want))
+	}
+
+	for name, deps := range want {
+		v, ok := got[name]
+		if !ok {
+			t.Errorf("GetAll() is missing %s", name)
+			continue
+		}
+
+		gotDeps := (*v).(map[string]interface{})["depends_on"].([]string)
+		if strings.Join(gotDeps, ",") != strings.Join(deps, ",") {
+			t.Errorf("GetAll()[%s] depends on %v, want %v", name, gotDeps, deps)
+		}
+	}
+}
+
+func TestProject_GetDependencies(t *testing.T) {
+	p := &Project{
+		Terraform: map[string]*Terraform{
+			"infra": {},
+			"rds":   {DependsOn: []string{"infra"}},
+			"cdn":   {DependsOn: []string{"infra"}},
+		},
+		Ecs: map[string]*Ecs{
+			"api":    {DependsOn: []string{"rds", "auth"}},
+			"auth":   {},
+			"worker": {DependsOn: []string{"api"}},
+		},
+	}
+
+	got := p.GetDependencies("api")
+
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if strings.Join(names, ",") != "api,auth,infra,rds" {
+		t.Errorf("GetDependencies() = %v, want [api auth infra rds]", names)
+	}
+}
+
+func TestTerraform_Dependencies(t *testing.T) {
+	tf := &Terraform{
+		DependsOn: []string{"vpc"},
+		Inputs: map[string]string{
+			"vpc_id":      "vpc.vpc_id",
+			"subnets":     "vpc.private_subnets",
+			"zone_id":     "dns.zone_id",
+			"db_endpoint": "rds.endpoint",
+		},
+	}
+
+	want := []string{"vpc", "dns", "rds"}
+	if got := tf.Dependencies(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Dependencies() = %v, want %v", got, want)
+	}
+}
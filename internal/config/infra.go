@@ -1,12 +1,30 @@
 package config
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
 type Infra struct {
 	Terraform Terraform `mapstructure:"infra.terraform,omitempty"`
 	Tunnel    Tunnel    `mapstructure:"infra.tunnel,omitempty"`
 }
 
+// Layouts of terraform stacks: a directory per env or a single directory with
+// a terraform workspace per env.
+const (
+	LayoutEnv       = "env"
+	LayoutWorkspace = "workspace"
+)
+
 type Terraform struct {
 	Version             string   `mapstructure:",omitempty"`
+	Engine              string   `mapstructure:"engine,omitempty"`
+	Layout              string   `mapstructure:"layout,omitempty"`
+	Path                string   `mapstructure:"path,omitempty"`
 	StateBucketRegion   string   `mapstructure:"state_bucket_region,omitempty"`
 	StateBucketName     string   `mapstructure:"state_bucket_name,omitempty"`
 	StateName           string   `mapstructure:"state_name,omitempty"`
@@ -15,6 +33,75 @@ type Terraform struct {
 	AwsRegion           string   `mapstructure:"aws_region,omitempty"`
 	AwsProfile          string   `mapstructure:"aws_profile,omitempty"`
 	DependsOn           []string `mapstructure:"depends_on,omitempty"`
+	PreventDestroyTypes []string `mapstructure:"prevent_destroy_types,omitempty"`
+	MaxDestroys         *int     `mapstructure:"max_destroys,omitempty"`
+	ProtectedResources  []string `mapstructure:"protected_resources,omitempty"`
+	LockPlatforms       []string `mapstructure:"lock_platforms,omitempty"`
+	Backend             Backend  `mapstructure:"backend,omitempty"`
+
+	// Vars override Inputs, which override VarFiles, which override the
+	// variables generated by ize. Vars and Inputs keep the case of their keys,
+	// see restoreTerraformVarNames.
+	Vars     map[string]interface{} `mapstructure:"vars,omitempty"`
+	VarFiles []string               `mapstructure:"var_files,omitempty"`
+
+	// Inputs map variables to outputs of other stacks as <stack>.<output>
+	Inputs map[string]string `mapstructure:"inputs,omitempty"`
+}
+
+// IsWorkspaceLayout reports whether the stack keeps the states of all envs in
+// terraform workspaces of a single directory.
+func (t *Terraform) IsWorkspaceLayout() bool {
+	return t != nil && t.Layout == LayoutWorkspace
+}
+
+// Dependencies returns the depends_on entries and the stacks the inputs are
+// read from.
+func (t *Terraform) Dependencies() []string {
+	deps := append([]string{}, t.DependsOn...)
+
+	stacks := make([]string, 0, len(t.Inputs))
+	for _, input := range t.Inputs {
+		stack, _, ok := strings.Cut(input, ".")
+		if ok && !stringContains(deps, stack) && !stringContains(stacks, stack) {
+			stacks = append(stacks, stack)
+		}
+	}
+	sort.Strings(stacks)
+
+	return append(deps, stacks...)
+}
+
+// Backend is the terraform state backend of a stack. Only the options of the
+// backend type are used.
+type Backend struct {
+	Type string `mapstructure:"type,omitempty"`
+
+	// s3
+	Bucket        string `mapstructure:"bucket,omitempty"`
+	Key           string `mapstructure:"key,omitempty"`
+	Region        string `mapstructure:"region,omitempty"`
+	Profile       string `mapstructure:"profile,omitempty"`
+	DynamoDBTable string `mapstructure:"dynamodb_table,omitempty"`
+	UseLockfile   bool   `mapstructure:"use_lockfile,omitempty"`
+
+	WorkspaceKeyPrefix string `mapstructure:"workspace_key_prefix,omitempty"`
+
+	// local
+	Path string `mapstructure:"path,omitempty"`
+
+	// http
+	Address              string `mapstructure:"address,omitempty"`
+	LockAddress          string `mapstructure:"lock_address,omitempty"`
+	UnlockAddress        string `mapstructure:"unlock_address,omitempty"`
+	LockMethod           string `mapstructure:"lock_method,omitempty"`
+	UnlockMethod         string `mapstructure:"unlock_method,omitempty"`
+	Username             string `mapstructure:"username,omitempty"`
+	SkipCertVerification bool   `mapstructure:"skip_cert_verification,omitempty"`
+
+	// pg
+	ConnStr    string `mapstructure:"conn_str,omitempty"`
+	SchemaName string `mapstructure:"schema_name,omitempty"`
 }
 
 type Tunnel struct {
@@ -23,3 +110,55 @@ type Tunnel struct {
 	SSHPublicKey      string   `mapstructure:"ssh_public_key,omitempty"`
 	SSHPrivateKey     string   `mapstructure:"ssh_private_key,omitempty"`
 }
+
+// Secrets configures the encryption of local secrets files.
+type Secrets struct {
+	// Age are age recipients local secrets files are encrypted to.
+	Age []string `mapstructure:"age,omitempty"`
+	// KMS are ARNs of the KMS keys local secrets files are encrypted with.
+	KMS []string `mapstructure:"kms,omitempty"`
+}
+
+// restoreTerraformVarNames reads vars and inputs of terraform stacks from the
+// config file again. viper lowercases map keys, but names of terraform
+// variables are case sensitive.
+func (p *Project) restoreTerraformVarNames(path string) error {
+	if len(path) == 0 || len(p.Terraform) == 0 {
+		return nil
+	}
+
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read config file %s: %w", path, err)
+	}
+
+	stacks, ok := tree.GetPath([]string{"terraform"}).(*toml.Tree)
+	if !ok {
+		return nil
+	}
+
+	for _, name := range stacks.Keys() {
+		tf, ok := p.Terraform[strings.ToLower(name)]
+		if !ok || tf == nil {
+			continue
+		}
+
+		stack, ok := stacks.GetPath([]string{name}).(*toml.Tree)
+		if !ok {
+			continue
+		}
+
+		if vars, ok := stack.GetPath([]string{"vars"}).(*toml.Tree); ok {
+			tf.Vars = vars.ToMap()
+		}
+
+		if inputs, ok := stack.GetPath([]string{"inputs"}).(*toml.Tree); ok {
+			tf.Inputs = map[string]string{}
+			for k, v := range inputs.ToMap() {
+				tf.Inputs[k] = fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"path/filepath"
+
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/aws/aws-sdk-go/service/ecs"
@@ -12,8 +16,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -50,6 +58,7 @@ type Project struct {
 	AWSClient *awsClient
 
 	Tunnel     *Tunnel                `mapstructure:",omitempty"`
+	Secrets    *Secrets               `mapstructure:",omitempty"`
 	Terraform  map[string]*Terraform  `mapstructure:",omitempty"`
 	Ecs        map[string]*Ecs        `mapstructure:",omitempty"`
 	Serverless map[string]*Serverless `mapstructure:",omitempty"`
@@ -66,6 +75,9 @@ type awsClient struct {
 	SSMClient            ssmiface.SSMAPI
 	ELBV2Client          elbv2iface.ELBV2API
 	ECRClient            ecriface.ECRAPI
+	CodeDeployClient     codedeployiface.CodeDeployAPI
+	SecretsManagerClient secretsmanageriface.SecretsManagerAPI
+	KMSClient            kmsiface.KMSAPI
 }
 
 type Option func(*awsClient)
@@ -118,6 +130,24 @@ func WithECRClient(api ecriface.ECRAPI) Option {
 	}
 }
 
+func WithCodeDeployClient(api codedeployiface.CodeDeployAPI) Option {
+	return func(r *awsClient) {
+		r.CodeDeployClient = api
+	}
+}
+
+func WithSecretsManagerClient(api secretsmanageriface.SecretsManagerAPI) Option {
+	return func(r *awsClient) {
+		r.SecretsManagerClient = api
+	}
+}
+
+func WithKMSClient(api kmsiface.KMSAPI) Option {
+	return func(r *awsClient) {
+		r.KMSClient = api
+	}
+}
+
 func NewAWSClient(options ...Option) *awsClient {
 	r := awsClient{}
 	for _, opt := range options {
@@ -137,6 +167,9 @@ func (p *Project) SettingAWSClient(sess *session.Session) {
 		WithSSMClient(ssm.New(sess)),
 		WithELBV2Client(elbv2.New(sess)),
 		WithECRClient(ecr.New(sess)),
+		WithCodeDeployClient(codedeploy.New(sess)),
+		WithSecretsManagerClient(secretsmanager.New(sess)),
+		WithKMSClient(kms.New(sess)),
 	)
 }
 
@@ -159,6 +192,14 @@ func (p *Project) GetApps() map[string]*interface{} {
 		apps[name] = &v
 	}
 
+	for name, body := range p.Helm {
+		var v interface{}
+		v = map[string]interface{}{
+			"depends_on": body.DependsOn,
+		}
+		apps[name] = &v
+	}
+
 	for name, body := range p.Alias {
 		var v interface{}
 		v = map[string]interface{}{
@@ -170,6 +211,28 @@ func (p *Project) GetApps() map[string]*interface{} {
 	return apps
 }
 
+// StackDir returns the directory of the terraform stack. It's the path of the
+// stack relative to the root dir if set, otherwise <ize_dir>/terraform/<name>
+// for the workspace layout and <env_dir>/<name> (<env_dir> for infra) for the
+// env layout.
+func (p *Project) StackDir(name string) string {
+	tf := p.Terraform[name]
+
+	switch {
+	case tf != nil && len(tf.Path) != 0:
+		if filepath.IsAbs(tf.Path) {
+			return tf.Path
+		}
+		return filepath.Join(p.RootDir, tf.Path)
+	case tf.IsWorkspaceLayout():
+		return filepath.Join(p.InfraDir, "terraform", name)
+	case name == "infra":
+		return p.EnvDir
+	default:
+		return filepath.Join(p.EnvDir, name)
+	}
+}
+
 func (p *Project) GetStates() map[string]*interface{} {
 	states := map[string]*interface{}{}
 
@@ -179,7 +242,7 @@ func (p *Project) GetStates() map[string]*interface{} {
 		}
 		var v interface{}
 		v = map[string]interface{}{
-			"depends_on": body.DependsOn,
+			"depends_on": body.Dependencies(),
 		}
 		states[name] = &v
 	}
@@ -2,7 +2,9 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -50,14 +52,29 @@ var (
 	}
 )
 
+// TraversalOption configures the traversal of the dependency graph
+type TraversalOption func(*traversalOptions)
+
+type traversalOptions struct {
+	parallelism int
+}
+
+// WithParallelism limits the number of apps processed at the same time.
+// A value less than 1 means there is no limit.
+func WithParallelism(n int) TraversalOption {
+	return func(o *traversalOptions) {
+		o.parallelism = n
+	}
+}
+
 // InDependencyOrder applies the function to the apps of the project taking in account the dependency order
-func InDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error) error {
-	return visit(ctx, apps, upDirectionTraversalConfig, fn, AppStopped)
+func InDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error, opts ...TraversalOption) error {
+	return visit(ctx, apps, upDirectionTraversalConfig, fn, AppStopped, opts...)
 }
 
 // InReverseDependencyOrder applies the function to the apps of the project in reverse order of dependencies
-func InReversDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error) error {
-	return visit(ctx, apps, downDirectionTraversalConfig, fn, AppStarted)
+func InReversDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error, opts ...TraversalOption) error {
+	return visit(ctx, apps, downDirectionTraversalConfig, fn, AppStarted, opts...)
 }
 
 // NewGraph returns the dependency graph of the apps
@@ -184,44 +201,132 @@ func remove(slice []string, item string) []string {
 	return s
 }
 
-func visit(ctx context.Context, apps map[string]*interface{}, traversalConfig graphTraversalConfig, fn func(context.Context, string) error, initialStatus AppStatus) error {
+func visit(ctx context.Context, apps map[string]*interface{}, traversalConfig graphTraversalConfig, fn func(context.Context, string) error, initialStatus AppStatus, opts ...TraversalOption) error {
 	g := NewGraph(apps, initialStatus)
 	if b, err := g.HasCycles(); b {
 		return err
 	}
 
-	nodes := traversalConfig.extremityNodesFn(g)
+	o := traversalOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	t := &traversal{
+		graph:     g,
+		config:    traversalConfig,
+		fn:        fn,
+		scheduled: map[string]bool{},
+	}
+
+	if o.parallelism > 0 {
+		t.sem = make(chan struct{}, o.parallelism)
+	}
+
+	t.run(ctx, traversalConfig.extremityNodesFn(g))
+	t.eg.Wait()
 
-	eg, _ := errgroup.WithContext(ctx)
-	eg.Go(func() error {
-		return run(ctx, g, eg, nodes, traversalConfig, fn)
-	})
+	return t.err()
+}
 
-	return eg.Wait()
+// traversal keeps the state of a single walk over the graph. A failed app
+// doesn't stop the apps that are already running, but the apps that depend
+// on it are never started.
+type traversal struct {
+	graph  *Graph
+	config graphTraversalConfig
+	fn     func(context.Context, string) error
+	eg     errgroup.Group
+	sem    chan struct{}
+
+	lock      sync.Mutex
+	scheduled map[string]bool
+	errs      []error
 }
 
-func run(ctx context.Context, graph *Graph, eg *errgroup.Group, nodes []*Vertex, traversalConfig graphTraversalConfig, fn func(context.Context, string) error) error {
+func (t *traversal) run(ctx context.Context, nodes []*Vertex) {
 	for _, node := range nodes {
 		// Don't start this app yet if all of its children have
 		// not been started yet.
-		if len(traversalConfig.filterAdjacentByStatusFn(graph, node.Key, traversalConfig.adjacentAppStatusToSkip)) != 0 {
+		if len(t.config.filterAdjacentByStatusFn(t.graph, node.Key, t.config.adjacentAppStatusToSkip)) != 0 {
+			continue
+		}
+
+		if !t.schedule(node.Key) {
 			continue
 		}
 
 		node := node
-		eg.Go(func() error {
-			err := fn(ctx, node.App)
+		t.eg.Go(func() error {
+			if t.sem != nil {
+				t.sem <- struct{}{}
+				defer func() { <-t.sem }()
+			}
+
+			if err := ctx.Err(); err != nil {
+				t.fail(node.App, err)
+				return nil
+			}
+
+			err := t.fn(ctx, node.App)
 			if err != nil {
-				return err
+				t.fail(node.App, err)
+				return nil
 			}
 
-			graph.UpdateStatus(node.Key, traversalConfig.targetAppStatus)
+			t.graph.UpdateStatus(node.Key, t.config.targetAppStatus)
+			t.run(ctx, t.config.adjacentNodesFn(node))
 
-			return run(ctx, graph, eg, traversalConfig.adjacentNodesFn(node), traversalConfig, fn)
+			return nil
 		})
 	}
+}
 
-	return nil
+// schedule marks the vertex as scheduled and reports whether it wasn't
+// scheduled before. Two apps finishing at the same time may both see their
+// common dependent as ready.
+func (t *traversal) schedule(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.scheduled[key] {
+		return false
+	}
+
+	t.scheduled[key] = true
+
+	return true
+}
+
+func (t *traversal) fail(app string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.errs = append(t.errs, fmt.Errorf("%s: %w", app, err))
+}
+
+func (t *traversal) err() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.errs) == 0 {
+		return nil
+	}
+
+	var cancelled []string
+	for key, v := range t.graph.Vertices {
+		if !t.scheduled[key] {
+			cancelled = append(cancelled, v.App)
+		}
+	}
+
+	errs := t.errs
+	if len(cancelled) != 0 {
+		sort.Strings(cancelled)
+		errs = append(errs, fmt.Errorf("cancelled because of failed dependencies: %s", strings.Join(cancelled, ", ")))
+	}
+
+	return errors.Join(errs...)
 }
 
 type graphTraversalConfig struct {
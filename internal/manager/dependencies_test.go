@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testApps(deps map[string][]string) map[string]*interface{} {
+	apps := map[string]*interface{}{}
+	for name, d := range deps {
+		var v interface{} = map[string]interface{}{
+			"depends_on": d,
+		}
+		apps[name] = &v
+	}
+
+	return apps
+}
+
+func TestInDependencyOrder(t *testing.T) {
+	deps := map[string][]string{
+		"squibby": {"goblin"},
+		"goblin":  {},
+		"bar":     {"goblin"},
+		"foo":     {"squibby", "bar"},
+	}
+	apps := testApps(deps)
+
+	var mu sync.Mutex
+	done := map[string]bool{}
+
+	err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range deps[name] {
+			if !done[d] {
+				return errors.New(name + " started before " + d)
+			}
+		}
+		if done[name] {
+			return errors.New(name + " started twice")
+		}
+		done[name] = true
+		return nil
+	}, WithParallelism(2))
+	if err != nil {
+		t.Fatalf("InDependencyOrder() error = %v", err)
+	}
+
+	if len(done) != len(apps) {
+		t.Errorf("InDependencyOrder() visited %d apps, want %d", len(done), len(apps))
+	}
+}
+
+func TestInDependencyOrder_Parallelism(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		want        int32
+	}{
+		{name: "sequential", parallelism: 1, want: 1},
+		{name: "limited", parallelism: 2, want: 2},
+		{name: "unlimited", parallelism: 0, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apps := testApps(map[string][]string{
+				"a": {}, "b": {}, "c": {}, "d": {},
+			})
+
+			var running, max int32
+			err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					m := atomic.LoadInt32(&max)
+					if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			}, WithParallelism(tt.parallelism))
+			if err != nil {
+				t.Fatalf("InDependencyOrder() error = %v", err)
+			}
+
+			if max != tt.want {
+				t.Errorf("InDependencyOrder() ran %d apps at the same time, want %d", max, tt.want)
+			}
+		})
+	}
+}
+
+func TestInDependencyOrder_Failure(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"db":     {},
+		"cache":  {},
+		"api":    {"db"},
+		"worker": {"api", "cache"},
+	})
+
+	var mu sync.Mutex
+	var visited []string
+
+	err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+		if name == "db" {
+			return errors.New("boom")
+		}
+		// give the failing sibling time to fail first
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		visited = append(visited, name)
+		mu.Unlock()
+		return nil
+	}, WithParallelism(0))
+	if err == nil {
+		t.Fatal("InDependencyOrder() expected error")
+	}
+
+	if len(visited) != 1 || visited[0] != "cache" {
+		t.Errorf("InDependencyOrder() visited %v, want [cache]", visited)
+	}
+
+	for _, want := range []string{"db: boom", "api, worker"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("InDependencyOrder() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestInReversDependencyOrder(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"db":  {},
+		"api": {"db"},
+	})
+
+	var order []string
+	err := InReversDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+		order = append(order, name)
+		return nil
+	}, WithParallelism(1))
+	if err != nil {
+		t.Fatalf("InReversDependencyOrder() error = %v", err)
+	}
+
+	if strings.Join(order, ",") != "api,db" {
+		t.Errorf("InReversDependencyOrder() order = %v, want [api db]", order)
+	}
+}
@@ -0,0 +1,210 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pterm/pterm"
+)
+
+var codeDeployPollInterval = 5 * time.Second
+
+type appSpec struct {
+	Version   json.Number       `json:"version"`
+	Resources []appSpecResource `json:"Resources"`
+}
+
+type appSpecResource struct {
+	TargetService appSpecTargetService `json:"TargetService"`
+}
+
+type appSpecTargetService struct {
+	Type       string            `json:"Type"`
+	Properties appSpecProperties `json:"Properties"`
+}
+
+type appSpecProperties struct {
+	TaskDefinition   string                   `json:"TaskDefinition"`
+	LoadBalancerInfo *appSpecLoadBalancerInfo `json:"LoadBalancerInfo,omitempty"`
+	PlatformVersion  string                   `json:"PlatformVersion,omitempty"`
+}
+
+type appSpecLoadBalancerInfo struct {
+	ContainerName string `json:"ContainerName"`
+	ContainerPort int64  `json:"ContainerPort"`
+}
+
+func isCodeDeploy(service *ecs.Service) bool {
+	return service.DeploymentController != nil && aws.StringValue(service.DeploymentController.Type) == ecs.DeploymentControllerTypeCodeDeploy
+}
+
+// newAppSpec returns the AppSpec of a blue/green deployment of the task
+// definition. The load balancer info is taken from the service, since
+// CodeDeploy needs it to shift the traffic.
+func newAppSpec(td *ecs.TaskDefinition, service *ecs.Service) (string, error) {
+	props := appSpecProperties{
+		TaskDefinition:  aws.StringValue(td.TaskDefinitionArn),
+		PlatformVersion: aws.StringValue(service.PlatformVersion),
+	}
+
+	if len(service.LoadBalancers) != 0 {
+		props.LoadBalancerInfo = &appSpecLoadBalancerInfo{
+			ContainerName: aws.StringValue(service.LoadBalancers[0].ContainerName),
+			ContainerPort: aws.Int64Value(service.LoadBalancers[0].ContainerPort),
+		}
+	}
+
+	b, err := json.Marshal(appSpec{
+		Version: "0.0",
+		Resources: []appSpecResource{
+			{
+				TargetService: appSpecTargetService{
+					Type:       "AWS::ECS::Service",
+					Properties: props,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// deployWithCodeDeploy deploys the task definition to a service with the
+// CODE_DEPLOY deployment controller and waits until the deployment finishes.
+// Failed deployments are rolled back by CodeDeploy according to the
+// deployment group settings.
+func (e *Manager) deployWithCodeDeploy(w io.Writer, td *ecs.TaskDefinition, service *ecs.Service, desiredCount *int64) error {
+	if desiredCount != nil {
+		_, err := e.Project.AWSClient.ECSClient.UpdateService(&ecs.UpdateServiceInput{
+			Service:      service.ServiceName,
+			Cluster:      aws.String(e.App.Cluster),
+			DesiredCount: desiredCount,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to update service: %w", err)
+		}
+	}
+
+	content, err := newAppSpec(td, service)
+	if err != nil {
+		return fmt.Errorf("can't create AppSpec: %w", err)
+	}
+
+	cd := e.Project.AWSClient.CodeDeployClient
+
+	input := &codedeploy.CreateDeploymentInput{
+		ApplicationName:     aws.String(e.App.CodeDeployApplication),
+		DeploymentGroupName: aws.String(e.App.CodeDeployGroup),
+		Description:         aws.String(fmt.Sprintf("Deployed by ize: %s:%d", aws.StringValue(td.Family), aws.Int64Value(td.Revision))),
+		Revision: &codedeploy.RevisionLocation{
+			RevisionType: aws.String(codedeploy.RevisionLocationTypeAppSpecContent),
+			AppSpecContent: &codedeploy.AppSpecContent{
+				Content: aws.String(content),
+			},
+		},
+	}
+
+	if len(e.App.DeploymentConfig) != 0 {
+		input.DeploymentConfigName = aws.String(e.App.DeploymentConfig)
+	}
+
+	cdo, err := cd.CreateDeployment(input)
+	if err != nil {
+		return fmt.Errorf("can't create CodeDeploy deployment: %w", err)
+	}
+
+	id := aws.StringValue(cdo.DeploymentId)
+	pterm.Fprintln(w, fmt.Sprintf("Created CodeDeploy deployment: %s (application: %s, deployment group: %s)", id, e.App.CodeDeployApplication, e.App.CodeDeployGroup))
+
+	events := map[string]string{}
+	waitingTimeout := time.Now().Add(time.Duration(e.App.Timeout) * time.Second)
+
+	for {
+		gdo, err := cd.GetDeployment(&codedeploy.GetDeploymentInput{
+			DeploymentId: aws.String(id),
+		})
+		if err != nil {
+			return fmt.Errorf("can't get CodeDeploy deployment %s: %w", id, err)
+		}
+
+		if err = printLifecycleEvents(w, cd, id, events); err != nil {
+			return err
+		}
+
+		info := gdo.DeploymentInfo
+		switch aws.StringValue(info.Status) {
+		case codedeploy.DeploymentStatusSucceeded:
+			pterm.Fprintln(w, fmt.Sprintf("CodeDeploy deployment %s succeeded", id))
+			return nil
+		case codedeploy.DeploymentStatusFailed, codedeploy.DeploymentStatusStopped:
+			reason := ""
+			if info.ErrorInformation != nil {
+				reason = aws.StringValue(info.ErrorInformation.Message)
+			}
+			return fmt.Errorf("CodeDeploy deployment %s %s: %s", id, aws.StringValue(info.Status), reason)
+		}
+
+		if time.Now().After(waitingTimeout) {
+			pterm.Fprintln(w, "Deployment failed due to timeout, stopping CodeDeploy deployment")
+			_, err = cd.StopDeployment(&codedeploy.StopDeploymentInput{
+				DeploymentId:        aws.String(id),
+				AutoRollbackEnabled: aws.Bool(true),
+			})
+			if err != nil {
+				return fmt.Errorf("can't stop CodeDeploy deployment %s: %w", id, err)
+			}
+
+			return fmt.Errorf("deployment failed due to timeout")
+		}
+
+		time.Sleep(codeDeployPollInterval)
+	}
+}
+
+// printLifecycleEvents prints the lifecycle events of the deployment targets
+// whose status has changed since the last call. Pending events are skipped.
+func printLifecycleEvents(w io.Writer, cd codedeployiface.CodeDeployAPI, id string, events map[string]string) error {
+	ldto, err := cd.ListDeploymentTargets(&codedeploy.ListDeploymentTargetsInput{
+		DeploymentId: aws.String(id),
+	})
+	if err != nil {
+		return fmt.Errorf("can't list CodeDeploy deployment targets: %w", err)
+	}
+
+	for _, target := range ldto.TargetIds {
+		gdto, err := cd.GetDeploymentTarget(&codedeploy.GetDeploymentTargetInput{
+			DeploymentId: aws.String(id),
+			TargetId:     target,
+		})
+		if err != nil {
+			return fmt.Errorf("can't get CodeDeploy deployment target: %w", err)
+		}
+
+		if gdto.DeploymentTarget == nil || gdto.DeploymentTarget.EcsTarget == nil {
+			continue
+		}
+
+		for _, event := range gdto.DeploymentTarget.EcsTarget.LifecycleEvents {
+			name := aws.StringValue(event.LifecycleEventName)
+			status := aws.StringValue(event.Status)
+
+			if events[name] == status || (len(events[name]) == 0 && status == codedeploy.LifecycleEventStatusPending) {
+				continue
+			}
+			events[name] = status
+
+			pterm.Fprintln(w, fmt.Sprintf("%s: %s", name, status))
+		}
+	}
+
+	return nil
+}
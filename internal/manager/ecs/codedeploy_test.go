@@ -0,0 +1,145 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+//go:generate mockgen -package=mocks -destination ../../../pkg/mocks/mock_codedeploy.go github.com/aws/aws-sdk-go/service/codedeploy/codedeployiface CodeDeployAPI
+
+func TestManager_deployWithCodeDeploy(t *testing.T) {
+	codeDeployPollInterval = 0
+
+	service := &ecs.Service{
+		ServiceName:          aws.String("test-api"),
+		DeploymentController: &ecs.DeploymentController{Type: aws.String(ecs.DeploymentControllerTypeCodeDeploy)},
+		LoadBalancers: []*ecs.LoadBalancer{
+			{ContainerName: aws.String("api"), ContainerPort: aws.Int64(3000)},
+		},
+	}
+	td := &ecs.TaskDefinition{
+		Family:            aws.String("test-api"),
+		Revision:          aws.Int64(2),
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/test-api:2"),
+	}
+
+	target := func(status string) *codedeploy.GetDeploymentTargetOutput {
+		return &codedeploy.GetDeploymentTargetOutput{
+			DeploymentTarget: &codedeploy.DeploymentTarget{
+				EcsTarget: &codedeploy.ECSTarget{
+					LifecycleEvents: []*codedeploy.LifecycleEvent{
+						{LifecycleEventName: aws.String("Install"), Status: aws.String(codedeploy.LifecycleEventStatusSucceeded)},
+						{LifecycleEventName: aws.String("AllowTraffic"), Status: aws.String(status)},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  string
+		wantOut  []string
+	}{
+		{
+			name:     "success",
+			statuses: []string{codedeploy.DeploymentStatusInProgress, codedeploy.DeploymentStatusSucceeded},
+			wantOut:  []string{"Install: Succeeded", "AllowTraffic: InProgress", "AllowTraffic: Succeeded", "succeeded"},
+		},
+		{
+			name:     "failed",
+			statuses: []string{codedeploy.DeploymentStatusInProgress, codedeploy.DeploymentStatusFailed},
+			wantErr:  "Failed: health checks failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockCodeDeployAPI(ctrl)
+
+			m.EXPECT().CreateDeployment(gomock.Any()).DoAndReturn(func(in *codedeploy.CreateDeploymentInput) (*codedeploy.CreateDeploymentOutput, error) {
+				if *in.ApplicationName != "AppECS-test-test-api" || *in.DeploymentGroupName != "DgpECS-test-test-api" {
+					t.Errorf("CreateDeployment() application = %s, group = %s", *in.ApplicationName, *in.DeploymentGroupName)
+				}
+
+				if aws.StringValue(in.DeploymentConfigName) != "CodeDeployDefault.ECSCanary10Percent5Minutes" {
+					t.Errorf("CreateDeployment() deployment config = %s", aws.StringValue(in.DeploymentConfigName))
+				}
+
+				var spec appSpec
+				if err := json.Unmarshal([]byte(*in.Revision.AppSpecContent.Content), &spec); err != nil {
+					t.Fatal(err)
+				}
+
+				props := spec.Resources[0].TargetService.Properties
+				if props.TaskDefinition != *td.TaskDefinitionArn || props.LoadBalancerInfo.ContainerName != "api" || props.LoadBalancerInfo.ContainerPort != 3000 {
+					t.Errorf("CreateDeployment() AppSpec = %s", *in.Revision.AppSpecContent.Content)
+				}
+
+				return &codedeploy.CreateDeploymentOutput{DeploymentId: aws.String("d-123")}, nil
+			}).Times(1)
+
+			for i, status := range tt.statuses {
+				info := &codedeploy.DeploymentInfo{Status: aws.String(status)}
+				if status == codedeploy.DeploymentStatusFailed {
+					info.ErrorInformation = &codedeploy.ErrorInformation{Message: aws.String("health checks failed")}
+				}
+				m.EXPECT().GetDeployment(gomock.Any()).Return(&codedeploy.GetDeploymentOutput{DeploymentInfo: info}, nil).Times(1)
+
+				eventStatus := codedeploy.LifecycleEventStatusInProgress
+				if i == len(tt.statuses)-1 {
+					eventStatus = codedeploy.LifecycleEventStatusSucceeded
+				}
+				m.EXPECT().ListDeploymentTargets(gomock.Any()).Return(&codedeploy.ListDeploymentTargetsOutput{
+					TargetIds: []*string{aws.String("test:test-api")},
+				}, nil).Times(1)
+				m.EXPECT().GetDeploymentTarget(gomock.Any()).Return(target(eventStatus), nil).Times(1)
+			}
+
+			e := &Manager{
+				Project: &config.Project{
+					AWSClient: config.NewAWSClient(config.WithCodeDeployClient(m)),
+				},
+				App: &config.Ecs{
+					Name:                  "api",
+					Cluster:               "test-test",
+					ServiceName:           "test-api",
+					Timeout:               60,
+					CodeDeployApplication: "AppECS-test-test-api",
+					CodeDeployGroup:       "DgpECS-test-test-api",
+					DeploymentConfig:      "CodeDeployDefault.ECSCanary10Percent5Minutes",
+				},
+			}
+
+			var out bytes.Buffer
+			err := e.deployWithCodeDeploy(&out, td, service, nil)
+			if len(tt.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("deployWithCodeDeploy() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("deployWithCodeDeploy() error = %v", err)
+			}
+
+			for _, line := range tt.wantOut {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("deployWithCodeDeploy() output doesn't contain %q:\n%s", line, out.String())
+				}
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ package ecs
 import (
 	"context"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	ecssvc "github.com/aws/aws-sdk-go/service/ecs"
 	"io"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
@@ -20,6 +22,10 @@ import (
 )
 
 func (e *Manager) deployWithDocker(w io.Writer) error {
+	if err := e.checkDockerDeploy(); err != nil {
+		return err
+	}
+
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return err
@@ -57,18 +63,53 @@ func (e *Manager) deployWithDocker(w io.Writer) error {
 		}
 	}
 
+	pinned, err := e.pinnedImages()
+	if err != nil {
+		return err
+	}
+
 	cmd := []string{"ecs", "deploy",
 		"--profile", e.App.AwsProfile,
 		"--region", e.App.AwsRegion,
 		e.App.Cluster,
 		fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name),
-		"--image", e.App.Name,
-		e.App.Image,
+	}
+
+	// ecs-deploy can't keep the repository of a container while changing its
+	// tag, so containers other than the app one need the native runtime
+	for _, name := range e.containers() {
+		if _, ok := pinned[name]; ok {
+			continue
+		}
+
+		if name != e.App.Name {
+			return fmt.Errorf("updating the image of container %s is only supported with the native runtime", name)
+		}
+
+		cmd = append(cmd, "--image", name, e.App.Image)
+	}
+
+	for _, name := range sortedKeys(pinned) {
+		cmd = append(cmd, "--image", name, pinned[name])
+	}
+
+	cmd = append(cmd,
 		"--diff",
 		"--timeout", strconv.Itoa(e.App.Timeout),
 		"--rollback",
 		"-e", e.App.Name,
 		"DD_VERSION", e.Project.Tag,
+	)
+
+	for _, v := range e.App.Environment {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || len(name) == 0 {
+			return fmt.Errorf("invalid environment entry %q: expected <name>=<value>", v)
+		}
+
+		for _, c := range e.containers() {
+			cmd = append(cmd, "-e", c, name, value)
+		}
 	}
 
 	cfg := container.Config{
@@ -134,6 +175,20 @@ func (e *Manager) deployWithDocker(w io.Writer) error {
 	}
 }
 
+// checkDockerDeploy returns an error for options ecs-deploy can't apply, so
+// they are not ignored silently.
+func (e *Manager) checkDockerDeploy() error {
+	if e.App.DesiredCount != nil || len(e.App.Cpu) != 0 || len(e.App.Memory) != 0 {
+		return fmt.Errorf("desired_count, cpu and memory are only supported with the native runtime")
+	}
+
+	if len(e.App.SecretsFrom) != 0 {
+		return fmt.Errorf("secrets_from is only supported with the native runtime")
+	}
+
+	return nil
+}
+
 func (e *Manager) redeployWithDocker(w io.Writer) error {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
@@ -172,26 +227,26 @@ func (e *Manager) redeployWithDocker(w io.Writer) error {
 		}
 	}
 
-	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+	name := e.App.ServiceName
+
+	dso, err := getService(name, e.App.Cluster, ecssvc.New(e.Project.Session))
+	if err != nil {
+		return err
+	}
+
+	family := taskDefinitionFamily(aws.StringValue(dso.Services[0].TaskDefinition))
 
 	var td string
 
 	switch e.App.TaskDefinitionRevision {
 	case "latest":
-		td = name
+		td = family
 	case "current":
-		dso, err := getService(name, e.App.Cluster, ecssvc.New(e.Project.Session))
-		if err != nil {
-			return err
-		}
-
 		td = *dso.Services[0].TaskDefinition
 	default:
-		r, err := strconv.Atoi(e.App.TaskDefinitionRevision)
-		if err == nil && r > 0 {
-			td = fmt.Sprintf("%s:%s", name, e.App.TaskDefinitionRevision)
-		} else {
-			return fmt.Errorf("invalid task definition revision: %s", e.App.TaskDefinitionRevision)
+		td, err = revisionTaskDefinition(e.App.TaskDefinitionRevision, family)
+		if err != nil {
+			return err
 		}
 	}
 
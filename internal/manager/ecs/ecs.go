@@ -28,6 +28,9 @@ import (
 
 const ecsDeployImage = "hazelops/ecs-deploy:latest"
 
+// SecretsFromSSM injects the SSM parameters of the app into the containers.
+const SecretsFromSSM = "ssm"
+
 type Manager struct {
 	Project *config.Project
 	App     *config.Ecs
@@ -69,6 +72,14 @@ func (e *Manager) prepare() {
 
 		}
 	}
+
+	if len(e.App.CodeDeployApplication) == 0 {
+		e.App.CodeDeployApplication = fmt.Sprintf("AppECS-%s-%s", e.App.Cluster, e.App.ServiceName)
+	}
+
+	if len(e.App.CodeDeployGroup) == 0 {
+		e.App.CodeDeployGroup = fmt.Sprintf("DgpECS-%s-%s", e.App.Cluster, e.App.ServiceName)
+	}
 }
 
 // Deploy deploys app container to ECS via ECS deploy
@@ -120,7 +131,6 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 
 	if e.Project.PreferRuntime == "native" {
 		err := e.deployLocal(s.TermOutput())
-		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
 			return fmt.Errorf("unable to deploy app: %w", err)
 		}
@@ -162,7 +172,6 @@ func (e *Manager) Redeploy(ui terminal.UI) error {
 
 	if e.Project.PreferRuntime == "native" {
 		err := e.redeployLocal(s.TermOutput())
-		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
 			return fmt.Errorf("unable to redeploy app: %w", err)
 		}
@@ -364,10 +373,8 @@ func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
 	}
 
 	if !autoApprove {
-		pterm.SetDefaultOutput(s.TermOutput())
-
-		pterm.Printfln("this will destroy the following:")
-		pterm.DefaultBulletList.WithItems(definitionsToBulletItems(definitions)).Render()
+		pterm.Fprintln(s.TermOutput(), "this will destroy the following:")
+		pterm.DefaultBulletList.WithWriter(s.TermOutput()).WithItems(definitionsToBulletItems(definitions)).Render()
 
 		isContinue, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Continue?").Show()
 		if err != nil {
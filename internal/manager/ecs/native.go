@@ -2,14 +2,18 @@ package ecs
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
@@ -36,6 +40,7 @@ func (e *Manager) deployLocal(w io.Writer) error {
 
 	dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: dso.Services[0].TaskDefinition,
+		Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
 	})
 	if err != nil {
 		return err
@@ -51,18 +56,22 @@ func (e *Manager) deployLocal(w io.Writer) error {
 
 	var oldTaskDef ecs.TaskDefinition
 	var newTaskDef ecs.TaskDefinition
+	var tags []*ecs.Tag
 
 	if len(definitions.TaskDefinitionArns) != 0 && *dtdo.TaskDefinition.TaskDefinitionArn != *definitions.TaskDefinitionArns[0] {
 		definition, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
 			TaskDefinition: definitions.TaskDefinitionArns[0],
+			Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
 		})
 		if err != nil {
 			return err
 		}
 
 		oldTaskDef = *definition.TaskDefinition
+		tags = definition.Tags
 	} else {
 		oldTaskDef = *dtdo.TaskDefinition
+		tags = dtdo.Tags
 	}
 
 	oldTaskDefJson, err := json.Marshal(oldTaskDef)
@@ -73,39 +82,32 @@ func (e *Manager) deployLocal(w io.Writer) error {
 	logrus.Debugf("oldTaskDef: %s", string(oldTaskDefJson))
 	pterm.Fprintln(w, fmt.Sprintf("Deploying based on task definition: %s:%d", *oldTaskDef.Family, *oldTaskDef.Revision))
 
-	var image string
+	// the old task definition is kept as is to show the diff and to roll back
+	var baseTaskDef ecs.TaskDefinition
+	if err = json.Unmarshal(oldTaskDefJson, &baseTaskDef); err != nil {
+		return fmt.Errorf("can't copy task definition: %w", err)
+	}
 
-	for i := 0; i < len(oldTaskDef.ContainerDefinitions); i++ {
-		container := oldTaskDef.ContainerDefinitions[i]
+	if err = e.updateContainerImages(w, baseTaskDef.ContainerDefinitions); err != nil {
+		return err
+	}
 
-		// We are changing the image/tag only for the app-specific container (not sidecars)
-		if *container.Name == e.App.Name {
-			if len(e.Project.Tag) != 0 && len(e.App.Image) == 0 {
-				name := strings.Split(*container.Image, ":")[0]
-				image = fmt.Sprintf("%s:%s", name, e.Project.Tag)
-			} else {
-				image = e.App.Image
-			}
+	if err = e.applyOverrides(&baseTaskDef); err != nil {
+		return err
+	}
 
-			pterm.Fprintln(w, fmt.Sprintf(`Changed image of container "%s" to : "%s" (was: "%s")`, *container.Name, image, *container.Image))
-			container.Image = &image
-		}
+	if err = e.applySecrets(&baseTaskDef); err != nil {
+		return err
+	}
+
+	printTaskDefinitionDiff(w, &oldTaskDef, &baseTaskDef)
+	if e.App.DesiredCount != nil && *e.App.DesiredCount != aws.Int64Value(dso.Services[0].DesiredCount) {
+		pterm.Fprintln(w, fmt.Sprintf("~ DesiredCount: %d -> %d", aws.Int64Value(dso.Services[0].DesiredCount), *e.App.DesiredCount))
 	}
 
 	pterm.Fprintln(w, "Creating new task definition revision")
 
-	rtdo, err := svc.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
-		ContainerDefinitions:    oldTaskDef.ContainerDefinitions,
-		Family:                  oldTaskDef.Family,
-		Volumes:                 oldTaskDef.Volumes,
-		TaskRoleArn:             oldTaskDef.TaskRoleArn,
-		ExecutionRoleArn:        oldTaskDef.ExecutionRoleArn,
-		RuntimePlatform:         oldTaskDef.RuntimePlatform,
-		RequiresCompatibilities: oldTaskDef.RequiresCompatibilities,
-		NetworkMode:             oldTaskDef.NetworkMode,
-		Cpu:                     oldTaskDef.Cpu,
-		Memory:                  oldTaskDef.Memory,
-	})
+	rtdo, err := svc.RegisterTaskDefinition(registerTaskDefinitionInput(&baseTaskDef, tags))
 	if err != nil {
 		return err
 	}
@@ -120,7 +122,16 @@ func (e *Manager) deployLocal(w io.Writer) error {
 	logrus.Debugf("newTaskDef: %s", string(newTaskDefJson))
 	pterm.Fprintln(w, fmt.Sprintf("Successfully created revision: %s:%d", *rtdo.TaskDefinition.Family, *rtdo.TaskDefinition.Revision))
 
-	if err = e.updateTaskDefinition(w, &newTaskDef, &oldTaskDef, e.App.ServiceName, "Deploying new task definition"); err != nil {
+	if isCodeDeploy(dso.Services[0]) {
+		// CodeDeploy rolls failed deployments back itself
+		if err = e.deployWithCodeDeploy(w, &newTaskDef, dso.Services[0], e.App.DesiredCount); err != nil {
+			return fmt.Errorf("deployment failed: %w", err)
+		}
+
+		return deregisterTaskDefinition(w, svc, &oldTaskDef)
+	}
+
+	if deployErr := e.updateTaskDefinition(w, &newTaskDef, &oldTaskDef, e.App.DesiredCount, e.App.ServiceName, "Deploying new task definition"); deployErr != nil {
 		err := e.getLastContainerLogs(w, fmt.Sprintf("%s", e.App.ServiceName))
 		if err != nil {
 			pterm.Fprintln(w, "Failed to get logs:", err)
@@ -128,17 +139,22 @@ func (e *Manager) deployLocal(w io.Writer) error {
 
 		sr, err := getStoppedReason(e.App.Cluster, e.App.ServiceName, svc)
 		if err != nil {
-			return err
+			pterm.Fprintln(w, "Failed to get stopped reason:", err)
+		} else {
+			pterm.Fprintln(w, fmt.Sprintf("Container %s couldn't start: %s", e.App.ServiceName, sr))
 		}
 
-		pterm.Fprintln(w, fmt.Sprintf("Container %s couldn't start: %s", e.App.ServiceName, sr))
+		if rolledBackByECS(deployErr, dso.Services[0]) {
+			pterm.Fprintln(w, "Deployment circuit breaker is enabled, ECS rolls the service back to the previous task definition")
+			return fmt.Errorf("deployment failed, the service is rolled back to previous task definition by the deployment circuit breaker: %s", *oldTaskDef.Family)
+		}
 
 		pterm.Fprintln(w, fmt.Sprintf("Rolling back to old task definition: %s:%d", *oldTaskDef.Family, *oldTaskDef.Revision))
 
 		e.App.Timeout = 600
 		logrus.Debugf("Setting timeout to %d seconds", e.App.Timeout)
 
-		if err = e.updateTaskDefinition(w, &oldTaskDef, &newTaskDef, e.App.ServiceName, "Deploying previous task definition"); err != nil {
+		if err = e.updateTaskDefinition(w, &oldTaskDef, &newTaskDef, dso.Services[0].DesiredCount, e.App.ServiceName, "Deploying previous task definition"); err != nil {
 			return fmt.Errorf("unable to rollback to old task definition: %w", err)
 		}
 
@@ -150,32 +166,440 @@ func (e *Manager) deployLocal(w io.Writer) error {
 	return nil
 }
 
-func (e *Manager) redeployLocal(w io.Writer) error {
-	pterm.SetDefaultOutput(w)
+// updateContainerImages changes the images of the containers listed in the app
+// containers to the new tag and sets the images pinned in container_images.
+// Other containers (sidecars) are left as they are.
+func (e *Manager) updateContainerImages(w io.Writer, containers []*ecs.ContainerDefinition) error {
+	pinned, err := e.pinnedImages()
+	if err != nil {
+		return err
+	}
 
-	svc := e.Project.AWSClient.ECSClient
+	updated := map[string]bool{}
+
+	for _, container := range containers {
+		var image string
+
+		if img, ok := pinned[*container.Name]; ok {
+			image = img
+		} else if containsString(e.containers(), *container.Name) {
+			image = e.newImage(*container.Name, *container.Image)
+		} else {
+			continue
+		}
+
+		pterm.Fprintln(w, fmt.Sprintf(`Changed image of container "%s" to : "%s" (was: "%s")`, *container.Name, image, *container.Image))
+		container.Image = aws.String(image)
+		updated[*container.Name] = true
+	}
+
+	// only explicitly configured containers have to exist, task definitions
+	// without the app container are deployed as before
+	for _, name := range e.App.Containers {
+		if !updated[name] {
+			return fmt.Errorf("container %s not found in task definition", name)
+		}
+	}
+
+	for name := range pinned {
+		if !updated[name] {
+			return fmt.Errorf("container %s not found in task definition", name)
+		}
+	}
+
+	return nil
+}
+
+// applyOverrides sets the task cpu and memory and the environment variables of
+// the updated containers from the app config.
+func (e *Manager) applyOverrides(td *ecs.TaskDefinition) error {
+	if len(e.App.Cpu) != 0 {
+		td.Cpu = aws.String(e.App.Cpu)
+	}
+
+	if len(e.App.Memory) != 0 {
+		td.Memory = aws.String(e.App.Memory)
+	}
+
+	if len(e.App.Environment) == 0 {
+		return nil
+	}
+
+	env := map[string]string{}
+	for _, v := range e.App.Environment {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || len(name) == 0 {
+			return fmt.Errorf("invalid environment entry %q: expected <name>=<value>", v)
+		}
+		env[name] = value
+	}
+
+	for _, container := range td.ContainerDefinitions {
+		if !containsString(e.containers(), *container.Name) {
+			continue
+		}
+
+		for _, kv := range container.Environment {
+			if value, ok := env[aws.StringValue(kv.Name)]; ok {
+				kv.Value = aws.String(value)
+			}
+		}
+
+		for _, name := range sortedKeys(env) {
+			if !hasEnvironmentVariable(container.Environment, name) {
+				container.Environment = append(container.Environment, &ecs.KeyValuePair{
+					Name:  aws.String(name),
+					Value: aws.String(env[name]),
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// applySecrets sets the secrets of the updated containers to the SSM
+// parameters pushed by ize secrets push to /<env>/<app> when secrets_from is
+// ssm. Secrets of parameters removed from the path are dropped, other secrets
+// are kept.
+func (e *Manager) applySecrets(td *ecs.TaskDefinition) error {
+	switch e.App.SecretsFrom {
+	case "":
+		return nil
+	case SecretsFromSSM:
+	default:
+		return fmt.Errorf("secrets_from %s is not supported, use %s", e.App.SecretsFrom, SecretsFromSSM)
+	}
+
+	path := fmt.Sprintf("/%s/%s", e.Project.Env, e.App.Name)
+
+	arns, err := parameterARNs(e.Project.AWSClient.SSMClient, path)
+	if err != nil {
+		return fmt.Errorf("can't get secrets of %s: %w", e.App.Name, err)
+	}
+
+	for _, container := range td.ContainerDefinitions {
+		if !containsString(e.containers(), *container.Name) {
+			continue
+		}
 
-	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+		var secrets []*ecs.Secret
 
-	dso, err := getService(name, e.App.Cluster, svc)
+		for _, secret := range container.Secrets {
+			name := aws.StringValue(secret.Name)
+			if _, ok := arns[name]; ok || isParameterOfPath(aws.StringValue(secret.ValueFrom), path) {
+				continue
+			}
+			secrets = append(secrets, secret)
+		}
+
+		for _, name := range sortedKeys(arns) {
+			secrets = append(secrets, &ecs.Secret{
+				Name:      aws.String(name),
+				ValueFrom: aws.String(arns[name]),
+			})
+		}
+
+		sort.SliceStable(secrets, func(i, j int) bool {
+			return aws.StringValue(secrets[i].Name) < aws.StringValue(secrets[j].Name)
+		})
+
+		container.Secrets = secrets
+	}
+
+	return nil
+}
+
+// parameterARNs returns the ARNs of the parameters under the path by the last
+// segment of their names, which is the environment variable name.
+func parameterARNs(api ssmiface.SSMAPI, path string) (map[string]string, error) {
+	arns := map[string]string{}
+
+	input := &ssm.GetParametersByPathInput{
+		Path: aws.String(path),
+	}
+
+	for {
+		out, err := api.GetParametersByPath(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range out.Parameters {
+			name := aws.StringValue(p.Name)
+			arns[name[strings.LastIndex(name, "/")+1:]] = aws.StringValue(p.ARN)
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	return arns, nil
+}
+
+// isParameterOfPath reports whether the ARN or name of an SSM parameter is
+// directly under the path.
+func isParameterOfPath(valueFrom string, path string) bool {
+	if i := strings.Index(valueFrom, ":parameter/"); i >= 0 {
+		valueFrom = valueFrom[i+len(":parameter"):]
+	}
+
+	name := strings.TrimPrefix(valueFrom, path+"/")
+
+	return name != valueFrom && !strings.Contains(name, "/")
+}
+
+func hasEnvironmentVariable(env []*ecs.KeyValuePair, name string) bool {
+	for _, kv := range env {
+		if aws.StringValue(kv.Name) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printTaskDefinitionDiff prints the fields that differ between the task
+// definitions. List items with a name (containers, environment variables,
+// volumes etc.) are matched by name instead of position.
+func printTaskDefinitionDiff(w io.Writer, oldTD *ecs.TaskDefinition, newTD *ecs.TaskDefinition) {
+	diff, err := taskDefinitionDiff(oldTD, newTD)
+	if err != nil {
+		logrus.Debugf("can't compare task definitions: %s", err)
+		return
+	}
+
+	if len(diff) == 0 {
+		pterm.Fprintln(w, "No changes in task definition")
+		return
+	}
+
+	pterm.Fprintln(w, "Task definition changes:")
+	for _, line := range diff {
+		pterm.Fprintln(w, line)
+	}
+}
+
+func taskDefinitionDiff(oldTD *ecs.TaskDefinition, newTD *ecs.TaskDefinition) ([]string, error) {
+	oldFields, err := flattenTaskDefinition(oldTD)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := flattenTaskDefinition(newTD)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+
+	for _, key := range sortedKeys(oldFields) {
+		newValue, ok := newFields[key]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("- %s: %s", key, oldFields[key]))
+		case newValue != oldFields[key]:
+			diff = append(diff, fmt.Sprintf("~ %s: %s -> %s", key, oldFields[key], newValue))
+		}
+	}
+
+	for _, key := range sortedKeys(newFields) {
+		if _, ok := oldFields[key]; !ok {
+			diff = append(diff, fmt.Sprintf("+ %s: %s", key, newFields[key]))
+		}
+	}
+
+	sort.SliceStable(diff, func(i, j int) bool {
+		return diff[i][2:] < diff[j][2:]
+	})
+
+	return diff, nil
+}
+
+func flattenTaskDefinition(td *ecs.TaskDefinition) (map[string]string, error) {
+	b, err := json.Marshal(td)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	flatten("", v, fields)
+
+	return fields, nil
+}
+
+func flatten(key string, v interface{}, fields map[string]string) {
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, val := range v {
+			if len(key) != 0 {
+				k = key + "." + k
+			}
+			flatten(k, val, fields)
+		}
+	case []interface{}:
+		for i, val := range v {
+			k := fmt.Sprintf("%s[%d]", key, i)
+			if m, ok := val.(map[string]interface{}); ok {
+				if name, ok := m["Name"].(string); ok {
+					k = fmt.Sprintf("%s[%s]", key, name)
+				}
+			}
+			flatten(k, val, fields)
+		}
+	default:
+		b, _ := json.Marshal(v)
+		fields[key] = string(b)
+	}
+}
+
+// containers returns the names of the containers that get the new image tag.
+func (e *Manager) containers() []string {
+	if len(e.App.Containers) == 0 {
+		return []string{e.App.Name}
+	}
+
+	return e.App.Containers
+}
+
+// newImage returns the image the container gets on deploy. The app container
+// gets the app image, other containers keep their repository and get the tag
+// (or digest) of the app image.
+func (e *Manager) newImage(name string, current string) string {
+	if len(e.Project.Tag) != 0 && len(e.App.Image) == 0 {
+		return fmt.Sprintf("%s:%s", imageRepository(current), e.Project.Tag)
+	}
+
+	if name == e.App.Name {
+		return e.App.Image
+	}
+
+	tag := strings.TrimPrefix(e.App.Image, imageRepository(e.App.Image))
+
+	return imageRepository(current) + tag
+}
+
+// pinnedImages parses container_images entries in the <container>=<image> form.
+func (e *Manager) pinnedImages() (map[string]string, error) {
+	pinned := map[string]string{}
+
+	for _, v := range e.App.ContainerImages {
+		name, image, ok := strings.Cut(v, "=")
+		if !ok || len(name) == 0 || len(image) == 0 {
+			return nil, fmt.Errorf("invalid container_images entry %q: expected <container>=<image>", v)
+		}
+		pinned[name] = image
+	}
+
+	return pinned, nil
+}
+
+// imageRepository returns the image without its tag or digest.
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	return image
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func containsString(array []string, needle string) bool {
+	for _, val := range array {
+		if val == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+// registerTaskDefinitionInput returns the input that registers a copy of the
+// task definition. Tags aren't part of ecs.TaskDefinition, they are returned
+// by DescribeTaskDefinition separately and have to be passed in.
+func registerTaskDefinitionInput(td *ecs.TaskDefinition, tags []*ecs.Tag) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    td.ContainerDefinitions,
+		Cpu:                     td.Cpu,
+		EphemeralStorage:        td.EphemeralStorage,
+		ExecutionRoleArn:        td.ExecutionRoleArn,
+		Family:                  td.Family,
+		InferenceAccelerators:   td.InferenceAccelerators,
+		IpcMode:                 td.IpcMode,
+		Memory:                  td.Memory,
+		NetworkMode:             td.NetworkMode,
+		PidMode:                 td.PidMode,
+		PlacementConstraints:    td.PlacementConstraints,
+		ProxyConfiguration:      td.ProxyConfiguration,
+		RequiresCompatibilities: td.RequiresCompatibilities,
+		RuntimePlatform:         td.RuntimePlatform,
+		Tags:                    tags,
+		TaskRoleArn:             td.TaskRoleArn,
+		Volumes:                 td.Volumes,
+	}
+}
+
+func (e *Manager) redeployLocal(w io.Writer) error {
+	svc := e.Project.AWSClient.ECSClient
+
+	dso, err := getService(e.App.ServiceName, e.App.Cluster, svc)
 	if err != nil {
 		return err
 	}
 
+	// the family can differ from the service name, so it's taken from the
+	// task definition of the service
+	family := taskDefinitionFamily(aws.StringValue(dso.Services[0].TaskDefinition))
+
 	var td *ecs.TaskDefinition
 
 	switch e.App.TaskDefinitionRevision {
 	case "latest":
 		tds, err := svc.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String(name),
+			FamilyPrefix: aws.String(family),
 			Sort:         aws.String("DESC"),
 		})
 		if err != nil {
 			return fmt.Errorf("unable to list task definitions: %w", err)
 		}
 
+		// the prefix matches other families too
+		var latest *string
+		for _, arn := range tds.TaskDefinitionArns {
+			if taskDefinitionFamily(aws.StringValue(arn)) == family {
+				latest = arn
+				break
+			}
+		}
+
+		if latest == nil {
+			return fmt.Errorf("no task definitions of %s found", family)
+		}
+
 		dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
-			TaskDefinition: tds.TaskDefinitionArns[0],
+			TaskDefinition: latest,
 		})
 		if err != nil {
 			return fmt.Errorf("unable to describe task definition: %w", err)
@@ -192,24 +616,31 @@ func (e *Manager) redeployLocal(w io.Writer) error {
 
 		td = dtdo.TaskDefinition
 	default:
-		r, err := strconv.Atoi(e.App.TaskDefinitionRevision)
-		if err == nil && r > 0 {
-			arn := fmt.Sprintf("%s:%s", name, e.App.TaskDefinitionRevision)
+		revision, err := revisionTaskDefinition(e.App.TaskDefinitionRevision, family)
+		if err != nil {
+			return err
+		}
 
-			dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
-				TaskDefinition: &arn,
-			})
-			if err != nil {
-				return fmt.Errorf("unable to describe task definition: %w", err)
-			}
+		dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: &revision,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to describe task definition: %w", err)
+		}
 
-			td = dtdo.TaskDefinition
-		} else {
-			return fmt.Errorf("invalid task definition revision: %s", e.App.TaskDefinitionRevision)
+		td = dtdo.TaskDefinition
+	}
+
+	if isCodeDeploy(dso.Services[0]) {
+		if err = e.deployWithCodeDeploy(w, td, dso.Services[0], nil); err != nil {
+			pterm.Fprintln(w, err)
+			return fmt.Errorf("redeployment failed")
 		}
+
+		return nil
 	}
 
-	if err = e.updateTaskDefinition(w, td, nil, name, "Redeploying new task definition"); err != nil {
+	if err = e.updateTaskDefinition(w, td, nil, nil, e.App.ServiceName, "Redeploying new task definition"); err != nil {
 		pterm.Fprintln(w, err)
 		err := e.getLastContainerLogs(w, fmt.Sprintf("%s", e.App.ServiceName))
 		if err != nil {
@@ -221,6 +652,32 @@ func (e *Manager) redeployLocal(w io.Writer) error {
 	return nil
 }
 
+// taskDefinitionFamily returns the family of a task definition ARN or
+// <family>:<revision>.
+func taskDefinitionFamily(arn string) string {
+	family := arn[strings.LastIndex(arn, "/")+1:]
+	if i := strings.LastIndex(family, ":"); i >= 0 {
+		family = family[:i]
+	}
+
+	return family
+}
+
+// revisionTaskDefinition returns <family>:<revision> of a revision, which is
+// either a number of the family or <family>:<number> like rollbacks set it.
+func revisionTaskDefinition(revision string, family string) (string, error) {
+	number := revision
+	if i := strings.LastIndex(revision, ":"); i >= 0 {
+		family, number = revision[:i], revision[i+1:]
+	}
+
+	if r, err := strconv.Atoi(number); err != nil || r <= 0 || len(family) == 0 {
+		return "", fmt.Errorf("invalid task definition revision: %s", revision)
+	}
+
+	return fmt.Sprintf("%s:%s", family, number), nil
+}
+
 func getService(name string, cluster string, svc ecsiface.ECSAPI) (*ecs.DescribeServicesOutput, error) {
 	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
 		Cluster:  &cluster,
@@ -236,7 +693,7 @@ func getService(name string, cluster string, svc ecsiface.ECSAPI) (*ecs.Describe
 	return dso, nil
 }
 
-func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, oldTD *ecs.TaskDefinition, serviceName string, title string) error {
+func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, oldTD *ecs.TaskDefinition, desiredCount *int64, serviceName string, title string) error {
 	pterm.Fprintln(w, fmt.Sprintf("Updating ECS service: %s (timeout: %d)", e.App.ServiceName, e.App.Timeout))
 
 	svc := e.Project.AWSClient.ECSClient
@@ -245,6 +702,7 @@ func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, o
 		Service:            aws.String(serviceName),
 		Cluster:            aws.String(e.App.Cluster),
 		TaskDefinition:     aws.String(*newTD.TaskDefinitionArn),
+		DesiredCount:       desiredCount,
 		ForceNewDeployment: aws.Bool(true),
 	})
 	if err != nil {
@@ -279,13 +737,26 @@ func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, o
 	waitingTimeout := time.Now().Add(time.Duration(e.App.Timeout) * time.Second)
 	waiting := true
 
+	var status, last deploymentStatus
+
 	for waiting && time.Now().Before(waitingTimeout) {
-		d, err := isDeployed(svc, serviceName, e.App.Cluster)
+		status, err = isDeployed(svc, serviceName, e.App.Cluster, aws.StringValue(newTD.TaskDefinitionArn))
 		if err != nil {
 			return err
 		}
 
-		waiting = !d
+		if status.running != last.running || status.pending != last.pending || status.desired != last.desired {
+			pterm.Fprintln(w, fmt.Sprintf("Tasks running: %d, pending: %d, desired: %d", status.running, status.pending, status.desired))
+		}
+		last = status
+
+		if status.failed {
+			printServiceEvents(w, status.events)
+			pterm.Fprintln(w, fmt.Sprintf("Deployment failed: %s", status.reason))
+			return &deploymentFailedError{reason: status.reason, rolledBack: status.rolledBack}
+		}
+
+		waiting = !status.done
 
 		if waiting {
 			time.Sleep(time.Second * 5)
@@ -293,6 +764,7 @@ func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, o
 	}
 
 	if waiting && time.Now().After(waitingTimeout) {
+		printServiceEvents(w, status.events)
 		pterm.Fprintln(w, "Deployment failed due to timeout")
 		return fmt.Errorf("deployment failed due to timeout")
 	}
@@ -319,21 +791,99 @@ func (e *Manager) updateTaskDefinition(w io.Writer, newTD *ecs.TaskDefinition, o
 	return nil
 }
 
-func isDeployed(svc ecsiface.ECSAPI, name string, cluster string) (bool, error) {
+// deploymentFailedError is returned when ECS reports that the deployment of
+// the new task definition failed.
+type deploymentFailedError struct {
+	reason string
+	// rolledBack is set when ECS already rolled the service back
+	rolledBack bool
+}
+
+func (e *deploymentFailedError) Error() string {
+	return fmt.Sprintf("deployment failed: %s", e.reason)
+}
+
+// deploymentStatus describes the progress of the primary deployment of a
+// service.
+type deploymentStatus struct {
+	done       bool
+	failed     bool
+	rolledBack bool
+	reason     string
+	running    int64
+	pending    int64
+	desired    int64
+	// events are the service events since the deployment started, oldest first
+	events []string
+}
+
+// isDeployed returns the status of the deployment of the task definition.
+// The rollout state is used when ECS reports it, otherwise the deployment is
+// done when the service has a single deployment with all desired tasks running.
+// The deployment failed if another task definition became primary, which is
+// how ECS rolls a service back.
+func isDeployed(svc ecsiface.ECSAPI, name string, cluster string, taskDefinition string) (deploymentStatus, error) {
+	var status deploymentStatus
+
 	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
 		Cluster:  &cluster,
 		Services: []*string{&name},
 	})
 	if err != nil {
-		return false, err
+		return status, err
 	}
 
 	if len(dso.Services) == 0 {
-		return false, nil
+		return status, nil
 	}
 
-	if len(dso.Services[0].Deployments) != 1 {
-		return false, nil
+	service := dso.Services[0]
+	status.running = aws.Int64Value(service.RunningCount)
+	status.pending = aws.Int64Value(service.PendingCount)
+	status.desired = aws.Int64Value(service.DesiredCount)
+
+	primary := primaryDeployment(service)
+	if primary != nil && primary.TaskDefinition != nil && aws.StringValue(primary.TaskDefinition) != taskDefinition {
+		status.failed = true
+		status.rolledBack = true
+		status.reason = fmt.Sprintf("the service was rolled back to %s", aws.StringValue(primary.TaskDefinition))
+
+		if d := taskDefinitionDeployment(service, taskDefinition); d != nil {
+			status.events = serviceEvents(service, aws.TimeValue(d.CreatedAt))
+			if reason := aws.StringValue(d.RolloutStateReason); reason != "" {
+				status.reason = fmt.Sprintf("%s: %s", status.reason, reason)
+			}
+		} else {
+			status.events = serviceEvents(service, aws.TimeValue(primary.CreatedAt))
+		}
+
+		return status, nil
+	}
+
+	if primary != nil {
+		status.events = serviceEvents(service, aws.TimeValue(primary.CreatedAt))
+
+		if primary.RunningCount != nil {
+			status.running = aws.Int64Value(primary.RunningCount)
+			status.pending = aws.Int64Value(primary.PendingCount)
+			status.desired = aws.Int64Value(primary.DesiredCount)
+		}
+
+		switch aws.StringValue(primary.RolloutState) {
+		case ecs.DeploymentRolloutStateCompleted:
+			status.done = true
+			return status, nil
+		case ecs.DeploymentRolloutStateFailed:
+			status.failed = true
+			status.reason = aws.StringValue(primary.RolloutStateReason)
+			return status, nil
+		case ecs.DeploymentRolloutStateInProgress:
+			return status, nil
+		}
+	}
+
+	if len(service.Deployments) != 1 {
+		return status, nil
 	}
 
 	runningTasks, err := svc.ListTasks(&ecs.ListTasksInput{
@@ -341,19 +891,95 @@ func isDeployed(svc ecsiface.ECSAPI, name string, cluster string) (bool, error)
 		ServiceName: &name,
 	})
 	if err != nil {
-		return false, err
+		return status, err
 	}
 
 	if len(runningTasks.TaskArns) == 0 {
-		return *dso.Services[0].DesiredCount == 0, nil
+		status.done = *service.DesiredCount == 0
+		return status, nil
 	}
 
-	runningCount, err := getRunningTaskCount(cluster, runningTasks.TaskArns, *dso.Services[0].TaskDefinition, svc)
+	runningCount, err := getRunningTaskCount(cluster, runningTasks.TaskArns, *service.TaskDefinition, svc)
 	if err != nil {
-		return false, err
+		return status, err
+	}
+
+	status.running = runningCount
+	status.done = runningCount == *service.DesiredCount
+
+	return status, nil
+}
+
+func printServiceEvents(w io.Writer, events []string) {
+	if len(events) == 0 {
+		return
+	}
+
+	pterm.Fprintln(w, "Service events:")
+	for _, event := range events {
+		pterm.Fprintln(w, "| "+event)
+	}
+}
+
+func primaryDeployment(service *ecs.Service) *ecs.Deployment {
+	for _, d := range service.Deployments {
+		if aws.StringValue(d.Status) == "PRIMARY" {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// taskDefinitionDeployment returns the deployment of the task definition,
+// nil if the service has none.
+func taskDefinitionDeployment(service *ecs.Service, taskDefinition string) *ecs.Deployment {
+	for _, d := range service.Deployments {
+		if aws.StringValue(d.TaskDefinition) == taskDefinition {
+			return d
+		}
 	}
 
-	return runningCount == *dso.Services[0].DesiredCount, nil
+	return nil
+}
+
+// serviceEvents returns the last service events created after the time,
+// oldest first. ECS returns the events newest first.
+func serviceEvents(service *ecs.Service, since time.Time) []string {
+	var events []string
+
+	for _, event := range service.Events {
+		if len(events) == 5 || aws.TimeValue(event.CreatedAt).Before(since) {
+			break
+		}
+		events = append([]string{aws.StringValue(event.Message)}, events...)
+	}
+
+	return events
+}
+
+// rollsBackOnFailure reports whether ECS rolls the service back by itself
+// when a deployment fails.
+// rolledBackByECS reports whether the deployment error means that ECS rolls
+// the service back itself. ECS rolls back only deployments it saw failing,
+// timeouts and API errors have to be rolled back by ize.
+func rolledBackByECS(err error, service *ecs.Service) bool {
+	var failed *deploymentFailedError
+	if !errors.As(err, &failed) {
+		return false
+	}
+
+	return failed.rolledBack || rollsBackOnFailure(service)
+}
+
+func rollsBackOnFailure(service *ecs.Service) bool {
+	if service.DeploymentConfiguration == nil || service.DeploymentConfiguration.DeploymentCircuitBreaker == nil {
+		return false
+	}
+
+	cb := service.DeploymentConfiguration.DeploymentCircuitBreaker
+
+	return aws.BoolValue(cb.Enable) && aws.BoolValue(cb.Rollback)
 }
 
 func getRunningTaskCount(cluster string, tasks []*string, serviceArn string, svc ecsiface.ECSAPI) (int64, error) {
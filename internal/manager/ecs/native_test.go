@@ -0,0 +1,578 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+// taskDefinitionReadOnlyFields are set by ECS and can't be registered.
+var taskDefinitionReadOnlyFields = map[string]bool{
+	"Compatibilities":    true,
+	"DeregisteredAt":     true,
+	"RegisteredAt":       true,
+	"RegisteredBy":       true,
+	"RequiresAttributes": true,
+	"Revision":           true,
+	"Status":             true,
+	"TaskDefinitionArn":  true,
+}
+
+// fillFields sets every exported field of the struct to a non-zero value.
+func fillFields(v reflect.Value, depth int) {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		fillValue(v.Field(i), v.Type().Field(i).Name, depth)
+	}
+}
+
+func fillValue(v reflect.Value, name string, depth int) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		switch v.Type().Elem() {
+		case reflect.TypeOf(""):
+			v.Set(reflect.ValueOf(aws.String(name)))
+		case reflect.TypeOf(int64(0)):
+			v.Set(reflect.ValueOf(aws.Int64(42)))
+		case reflect.TypeOf(false):
+			v.Set(reflect.ValueOf(aws.Bool(true)))
+		case reflect.TypeOf(time.Time{}):
+			v.Set(reflect.ValueOf(aws.Time(time.Unix(1600000000, 0))))
+		default:
+			if v.Type().Elem().Kind() != reflect.Struct || depth > 5 {
+				return
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+			fillFields(v.Elem(), depth+1)
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillValue(v.Index(0), name, depth)
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key := reflect.New(v.Type().Key()).Elem()
+		key.SetString(name)
+		val := reflect.New(v.Type().Elem()).Elem()
+		fillValue(val, name, depth)
+		v.SetMapIndex(key, val)
+	}
+}
+
+func Test_registerTaskDefinitionInput(t *testing.T) {
+	td := &ecs.TaskDefinition{}
+	fillFields(reflect.ValueOf(td).Elem(), 0)
+
+	tags := []*ecs.Tag{{Key: aws.String("cost-center"), Value: aws.String("platform")}}
+
+	in := registerTaskDefinitionInput(td, tags)
+
+	tdValue := reflect.ValueOf(td).Elem()
+	inValue := reflect.ValueOf(in).Elem()
+
+	for i := 0; i < tdValue.NumField(); i++ {
+		field := tdValue.Type().Field(i)
+		if field.PkgPath != "" || taskDefinitionReadOnlyFields[field.Name] {
+			continue
+		}
+
+		got := inValue.FieldByName(field.Name)
+		if !got.IsValid() {
+			t.Errorf("TaskDefinition.%s is neither registerable nor known as read-only", field.Name)
+			continue
+		}
+
+		if got.IsZero() {
+			t.Errorf("registerTaskDefinitionInput() dropped %s", field.Name)
+			continue
+		}
+
+		if !reflect.DeepEqual(got.Interface(), tdValue.Field(i).Interface()) {
+			t.Errorf("registerTaskDefinitionInput() %s = %v, want %v", field.Name, got.Interface(), tdValue.Field(i).Interface())
+		}
+	}
+
+	for i := 0; i < inValue.NumField(); i++ {
+		field := inValue.Type().Field(i)
+		if field.PkgPath != "" || field.Name == "Tags" {
+			continue
+		}
+
+		if _, ok := tdValue.Type().FieldByName(field.Name); !ok {
+			t.Errorf("RegisterTaskDefinitionInput.%s has no counterpart in TaskDefinition", field.Name)
+		}
+	}
+
+	if !reflect.DeepEqual(in.Tags, tags) {
+		t.Errorf("registerTaskDefinitionInput() Tags = %v, want %v", in.Tags, tags)
+	}
+}
+
+func TestManager_updateContainerImages(t *testing.T) {
+	containers := func() []*ecs.ContainerDefinition {
+		return []*ecs.ContainerDefinition{
+			{Name: aws.String("api"), Image: aws.String("registry.example.com:5000/testnut-api:old")},
+			{Name: aws.String("worker"), Image: aws.String("registry.example.com:5000/testnut-worker:old")},
+			{Name: aws.String("datadog-agent"), Image: aws.String("datadog/agent:7")},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		app     config.Ecs
+		tag     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "app container only",
+			app:  config.Ecs{Name: "api", Image: "registry.example.com:5000/testnut-api:new"},
+			want: []string{
+				"registry.example.com:5000/testnut-api:new",
+				"registry.example.com:5000/testnut-worker:old",
+				"datadog/agent:7",
+			},
+		},
+		{
+			name: "several containers and a pinned sidecar",
+			app: config.Ecs{
+				Name:            "api",
+				Image:           "registry.example.com:5000/testnut-api:new",
+				Containers:      []string{"api", "worker"},
+				ContainerImages: []string{"datadog-agent=datadog/agent:7.50.0"},
+			},
+			want: []string{
+				"registry.example.com:5000/testnut-api:new",
+				"registry.example.com:5000/testnut-worker:new",
+				"datadog/agent:7.50.0",
+			},
+		},
+		{
+			name: "project tag",
+			app:  config.Ecs{Name: "api", Containers: []string{"api", "worker"}},
+			tag:  "abc123",
+			want: []string{
+				"registry.example.com:5000/testnut-api:abc123",
+				"registry.example.com:5000/testnut-worker:abc123",
+				"datadog/agent:7",
+			},
+		},
+		{
+			name:    "unknown container",
+			app:     config.Ecs{Name: "api", Image: "testnut-api:new", Containers: []string{"api", "migrate"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pinned image",
+			app:     config.Ecs{Name: "api", Image: "testnut-api:new", ContainerImages: []string{"datadog-agent"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := tt.app
+
+			e := &Manager{
+				Project: &config.Project{Tag: tt.tag},
+				App:     &app,
+			}
+
+			cd := containers()
+			err := e.updateContainerImages(io.Discard, cd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("updateContainerImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for i, c := range cd {
+				if *c.Image != tt.want[i] {
+					t.Errorf("updateContainerImages() %s image = %s, want %s", *c.Name, *c.Image, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestManager_applyOverrides(t *testing.T) {
+	td := &ecs.TaskDefinition{
+		Cpu:    aws.String("256"),
+		Memory: aws.String("512"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("api"),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("LOG_LEVEL"), Value: aws.String("info")},
+					{Name: aws.String("PORT"), Value: aws.String("3000")},
+				},
+			},
+			{Name: aws.String("datadog-agent")},
+		},
+	}
+
+	b, err := json.Marshal(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var oldTD ecs.TaskDefinition
+	if err = json.Unmarshal(b, &oldTD); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Manager{
+		Project: &config.Project{},
+		App: &config.Ecs{
+			Name:        "api",
+			Containers:  []string{"api"},
+			Cpu:         "512",
+			Memory:      "1024",
+			Environment: []string{"LOG_LEVEL=debug", "FEATURE_X=a=b"},
+		},
+	}
+
+	if err = e.applyOverrides(td); err != nil {
+		t.Fatalf("applyOverrides() error = %v", err)
+	}
+
+	diff, err := taskDefinitionDiff(&oldTD, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`+ ContainerDefinitions[api].Environment[FEATURE_X].Name: "FEATURE_X"`,
+		`+ ContainerDefinitions[api].Environment[FEATURE_X].Value: "a=b"`,
+		`~ ContainerDefinitions[api].Environment[LOG_LEVEL].Value: "info" -> "debug"`,
+		`~ Cpu: "256" -> "512"`,
+		`~ Memory: "512" -> "1024"`,
+	}
+
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("taskDefinitionDiff() = %q, want %q", diff, want)
+	}
+
+	if len(td.ContainerDefinitions[1].Environment) != 0 {
+		t.Errorf("applyOverrides() changed the environment of the datadog-agent container")
+	}
+
+	e.App.Environment = []string{"LOG_LEVEL"}
+	if err = e.applyOverrides(td); err == nil {
+		t.Errorf("applyOverrides() accepted an invalid environment entry")
+	}
+}
+
+func TestManager_applySecrets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	arn := func(name string) *string {
+		return aws.String("arn:aws:ssm:us-east-1:123456789012:parameter/testnut/api/" + name)
+	}
+
+	td := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Name: aws.String("api"),
+				Secrets: []*ecs.Secret{
+					{Name: aws.String("API_KEY"), ValueFrom: arn("API_KEY")},
+					{Name: aws.String("DD_API_KEY"), ValueFrom: aws.String("/testnut/datadog/DD_API_KEY")},
+					{Name: aws.String("OLD_KEY"), ValueFrom: arn("OLD_KEY")},
+				},
+			},
+			{Name: aws.String("datadog-agent")},
+		},
+	}
+
+	b, err := json.Marshal(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var oldTD ecs.TaskDefinition
+	if err = json.Unmarshal(b, &oldTD); err != nil {
+		t.Fatal(err)
+	}
+
+	m := mocks.NewMockSSMAPI(ctrl)
+	m.EXPECT().GetParametersByPath(&ssm.GetParametersByPathInput{
+		Path: aws.String("/testnut/api"),
+	}).Return(&ssm.GetParametersByPathOutput{
+		Parameters: []*ssm.Parameter{
+			{Name: aws.String("/testnut/api/API_KEY"), ARN: arn("API_KEY")},
+		},
+		NextToken: aws.String("next"),
+	}, nil)
+	m.EXPECT().GetParametersByPath(&ssm.GetParametersByPathInput{
+		Path:      aws.String("/testnut/api"),
+		NextToken: aws.String("next"),
+	}).Return(&ssm.GetParametersByPathOutput{
+		Parameters: []*ssm.Parameter{
+			{Name: aws.String("/testnut/api/DB_PASSWORD"), ARN: arn("DB_PASSWORD")},
+		},
+	}, nil)
+
+	e := &Manager{
+		Project: &config.Project{
+			Env:       "testnut",
+			AWSClient: config.NewAWSClient(config.WithSSMClient(m)),
+		},
+		App: &config.Ecs{
+			Name:        "api",
+			Containers:  []string{"api"},
+			SecretsFrom: SecretsFromSSM,
+		},
+	}
+
+	if err = e.applySecrets(td); err != nil {
+		t.Fatalf("applySecrets() error = %v", err)
+	}
+
+	diff, err := taskDefinitionDiff(&oldTD, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`+ ContainerDefinitions[api].Secrets[DB_PASSWORD].Name: "DB_PASSWORD"`,
+		`+ ContainerDefinitions[api].Secrets[DB_PASSWORD].ValueFrom: "arn:aws:ssm:us-east-1:123456789012:parameter/testnut/api/DB_PASSWORD"`,
+		`- ContainerDefinitions[api].Secrets[OLD_KEY].Name: "OLD_KEY"`,
+		`- ContainerDefinitions[api].Secrets[OLD_KEY].ValueFrom: "arn:aws:ssm:us-east-1:123456789012:parameter/testnut/api/OLD_KEY"`,
+	}
+
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("taskDefinitionDiff() = %q, want %q", diff, want)
+	}
+
+	if len(td.ContainerDefinitions[1].Secrets) != 0 {
+		t.Errorf("applySecrets() set secrets of the datadog-agent container")
+	}
+
+	e.App.SecretsFrom = "vault"
+	if err = e.applySecrets(td); err == nil {
+		t.Errorf("applySecrets() accepted an unsupported secrets_from")
+	}
+}
+
+func Test_isDeployed(t *testing.T) {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	events := []*ecs.ServiceEvent{
+		{CreatedAt: aws.Time(started.Add(2 * time.Minute)), Message: aws.String("(service test-api) deployment failed: tasks failed to start.")},
+		{CreatedAt: aws.Time(started.Add(time.Minute)), Message: aws.String("(service test-api) has started 1 tasks.")},
+		{CreatedAt: aws.Time(started.Add(-time.Hour)), Message: aws.String("(service test-api) has reached a steady state.")},
+	}
+
+	primary := func(state, reason string) []*ecs.Deployment {
+		return []*ecs.Deployment{
+			{
+				Status:             aws.String("PRIMARY"),
+				TaskDefinition:     aws.String("test-arn:2"),
+				CreatedAt:          aws.Time(started),
+				RolloutState:       aws.String(state),
+				RolloutStateReason: aws.String(reason),
+				RunningCount:       aws.Int64(1),
+				PendingCount:       aws.Int64(1),
+				DesiredCount:       aws.Int64(2),
+			},
+			{
+				Status:         aws.String("ACTIVE"),
+				TaskDefinition: aws.String("test-arn:1"),
+				RolloutState:   aws.String(ecs.DeploymentRolloutStateCompleted),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		service *ecs.Service
+		mockECS func(m *mocks.MockECSAPI)
+		want    deploymentStatus
+	}{
+		{
+			name:    "in progress",
+			service: &ecs.Service{Deployments: primary(ecs.DeploymentRolloutStateInProgress, ""), Events: events},
+			want: deploymentStatus{
+				running: 1, pending: 1, desired: 2,
+				events: []string{"(service test-api) has started 1 tasks.", "(service test-api) deployment failed: tasks failed to start."},
+			},
+		},
+		{
+			name:    "completed",
+			service: &ecs.Service{Deployments: primary(ecs.DeploymentRolloutStateCompleted, "")},
+			want:    deploymentStatus{done: true, running: 1, pending: 1, desired: 2},
+		},
+		{
+			name:    "failed",
+			service: &ecs.Service{Deployments: primary(ecs.DeploymentRolloutStateFailed, "ECS deployment circuit breaker: tasks failed to start."), Events: events[2:]},
+			want:    deploymentStatus{failed: true, reason: "ECS deployment circuit breaker: tasks failed to start.", running: 1, pending: 1, desired: 2},
+		},
+		{
+			name: "rolled back",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("test-arn:1"),
+						CreatedAt:      aws.Time(started.Add(3 * time.Minute)),
+						RolloutState:   aws.String(ecs.DeploymentRolloutStateCompleted),
+						RunningCount:   aws.Int64(2),
+						DesiredCount:   aws.Int64(2),
+					},
+					{
+						Status:             aws.String("ACTIVE"),
+						TaskDefinition:     aws.String("test-arn:2"),
+						CreatedAt:          aws.Time(started),
+						RolloutState:       aws.String(ecs.DeploymentRolloutStateFailed),
+						RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+					},
+				},
+				Events: events,
+			},
+			want: deploymentStatus{
+				failed:     true,
+				rolledBack: true,
+				reason:     "the service was rolled back to test-arn:1: ECS deployment circuit breaker: tasks failed to start.",
+				events:     []string{"(service test-api) has started 1 tasks.", "(service test-api) deployment failed: tasks failed to start."},
+			},
+		},
+		{
+			name: "rolled back and removed",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("test-arn:1"),
+						CreatedAt:      aws.Time(started.Add(3 * time.Minute)),
+						RolloutState:   aws.String(ecs.DeploymentRolloutStateCompleted),
+					},
+				},
+			},
+			want: deploymentStatus{failed: true, rolledBack: true, reason: "the service was rolled back to test-arn:1"},
+		},
+		{
+			name: "no rollout state",
+			service: &ecs.Service{
+				TaskDefinition: aws.String("test-arn"),
+				DesiredCount:   aws.Int64(1),
+				Deployments:    []*ecs.Deployment{{}},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{
+					TaskArns: []*string{aws.String("test")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{LastStatus: aws.String("RUNNING"), TaskDefinitionArn: aws.String("test-arn")}},
+				}, nil).Times(1)
+			},
+			want: deploymentStatus{done: true, running: 1, desired: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockECSAPI(ctrl)
+			m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+				Services: []*ecs.Service{tt.service},
+			}, nil).Times(1)
+			if tt.mockECS != nil {
+				tt.mockECS(m)
+			}
+
+			got, err := isDeployed(m, "test-api", "test-test", "test-arn:2")
+			if err != nil {
+				t.Fatalf("isDeployed() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("isDeployed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rolledBackByECS(t *testing.T) {
+	circuitBreaker := &ecs.Service{
+		DeploymentConfiguration: &ecs.DeploymentConfiguration{
+			DeploymentCircuitBreaker: &ecs.DeploymentCircuitBreaker{Enable: aws.Bool(true), Rollback: aws.Bool(true)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		service *ecs.Service
+		want    bool
+	}{
+		{name: "failed with circuit breaker", err: &deploymentFailedError{reason: "tasks failed to start"}, service: circuitBreaker, want: true},
+		{name: "failed without circuit breaker", err: &deploymentFailedError{reason: "tasks failed to start"}, service: &ecs.Service{}, want: false},
+		{name: "rolled back", err: fmt.Errorf("can't deploy: %w", &deploymentFailedError{rolledBack: true}), service: &ecs.Service{}, want: true},
+		{name: "timeout", err: fmt.Errorf("deployment failed due to timeout"), service: circuitBreaker, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rolledBackByECS(tt.err, tt.service); got != tt.want {
+				t.Errorf("rolledBackByECS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_revisionTaskDefinition(t *testing.T) {
+	tests := []struct {
+		revision string
+		want     string
+		wantErr  bool
+	}{
+		{revision: "3", want: "testnut-goblin-task:3"},
+		{revision: "goblin:7", want: "goblin:7"},
+		{revision: "0", wantErr: true},
+		{revision: "goblin:latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.revision, func(t *testing.T) {
+			got, err := revisionTaskDefinition(tt.revision, taskDefinitionFamily("arn:aws:ecs:us-east-1:123456789012:task-definition/testnut-goblin-task:12"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("revisionTaskDefinition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("revisionTaskDefinition() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_checkDockerDeploy(t *testing.T) {
+	tests := []struct {
+		name    string
+		app     *config.Ecs
+		wantErr bool
+	}{
+		{name: "image only", app: &config.Ecs{Name: "api"}},
+		{name: "cpu", app: &config.Ecs{Name: "api", Cpu: "512"}, wantErr: true},
+		{name: "secrets from ssm", app: &config.Ecs{Name: "api", SecretsFrom: SecretsFromSSM}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Manager{Project: &config.Project{}, App: tt.app}
+			if err := e.checkDockerDeploy(); (err != nil) != tt.wantErr {
+				t.Errorf("checkDockerDeploy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
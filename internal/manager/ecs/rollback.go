@@ -0,0 +1,152 @@
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/hazelops/ize/internal/aws/utils"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+// Revision is a task definition revision of the app service.
+type Revision struct {
+	Family       string
+	Number       int64
+	Status       string
+	Image        string
+	RegisteredAt time.Time
+	Current      bool
+}
+
+// Tag returns the image tag of the app container.
+func (r Revision) Tag() string {
+	return r.Image[len(imageRepository(r.Image)):]
+}
+
+// Revisions returns the last task definition revisions of the service, both
+// active and inactive ones, newest first.
+func (e *Manager) Revisions(limit int) ([]Revision, error) {
+	e.prepare()
+
+	if len(e.App.AwsRegion) != 0 && len(e.App.AwsProfile) != 0 {
+		sess, err := utils.GetSession(&utils.SessionConfig{
+			Region:      e.App.AwsRegion,
+			Profile:     e.App.AwsProfile,
+			EndpointUrl: e.Project.EndpointUrl,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get session: %w", err)
+		}
+
+		e.Project.SettingAWSClient(sess)
+	}
+
+	svc := e.Project.AWSClient.ECSClient
+
+	dso, err := getService(e.App.ServiceName, e.App.Cluster, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: dso.Services[0].TaskDefinition,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe task definition: %w", err)
+	}
+
+	family := aws.StringValue(current.TaskDefinition.Family)
+
+	var arns []*string
+	for _, status := range []string{ecs.TaskDefinitionStatusActive, ecs.TaskDefinitionStatusInactive} {
+		ltdo, err := svc.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
+			FamilyPrefix: aws.String(family),
+			Sort:         aws.String(ecs.SortOrderDesc),
+			Status:       aws.String(status),
+			MaxResults:   aws.Int64(int64(limit)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list task definitions: %w", err)
+		}
+
+		arns = append(arns, ltdo.TaskDefinitionArns...)
+	}
+
+	var revisions []Revision
+	for _, arn := range arns {
+		dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: arn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe task definition: %w", err)
+		}
+
+		td := dtdo.TaskDefinition
+		if aws.StringValue(td.Family) != family {
+			// the prefix matches other families too
+			continue
+		}
+
+		r := Revision{
+			Family:       family,
+			Number:       aws.Int64Value(td.Revision),
+			Status:       aws.StringValue(td.Status),
+			RegisteredAt: aws.TimeValue(td.RegisteredAt),
+			Current:      aws.StringValue(td.TaskDefinitionArn) == aws.StringValue(current.TaskDefinition.TaskDefinitionArn),
+		}
+
+		for _, c := range td.ContainerDefinitions {
+			if aws.StringValue(c.Name) == e.App.Name || len(r.Image) == 0 {
+				r.Image = aws.StringValue(c.Image)
+			}
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number > revisions[j].Number
+	})
+
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+
+	return revisions, nil
+}
+
+// Rollback redeploys the revision of the service task definition. ECS can't
+// deploy inactive revisions, so they are registered again as a new revision.
+func (e *Manager) Rollback(ui terminal.UI, revision Revision) error {
+	e.prepare()
+
+	number := revision.Number
+
+	if revision.Status == ecs.TaskDefinitionStatusInactive {
+		svc := e.Project.AWSClient.ECSClient
+
+		dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: aws.String(fmt.Sprintf("%s:%d", revision.Family, revision.Number)),
+			Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to describe task definition: %w", err)
+		}
+
+		rtdo, err := svc.RegisterTaskDefinition(registerTaskDefinitionInput(dtdo.TaskDefinition, dtdo.Tags))
+		if err != nil {
+			return fmt.Errorf("unable to register inactive task definition %s:%d again: %w", revision.Family, revision.Number, err)
+		}
+
+		number = aws.Int64Value(rtdo.TaskDefinition.Revision)
+		ui.Output("Registered inactive revision %d as %s:%d", revision.Number, revision.Family, number)
+	}
+
+	// the family is set too, it can differ from the service name
+	e.App.TaskDefinitionRevision = fmt.Sprintf("%s:%d", revision.Family, number)
+
+	return e.Redeploy(ui)
+}
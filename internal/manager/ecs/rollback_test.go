@@ -0,0 +1,163 @@
+package ecs
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func TestManager_Revisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	registered := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	arn := func(family string, rev int64) *string {
+		return aws.String(fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:task-definition/%s:%d", family, rev))
+	}
+
+	definitions := map[string]*ecs.TaskDefinition{}
+	for _, td := range []struct {
+		family string
+		rev    int64
+		status string
+		tag    string
+	}{
+		{"test-api", 3, ecs.TaskDefinitionStatusActive, "c3"},
+		{"test-api", 2, ecs.TaskDefinitionStatusActive, "b2"},
+		{"test-api", 1, ecs.TaskDefinitionStatusInactive, "a1"},
+		{"test-api-worker", 1, ecs.TaskDefinitionStatusActive, "w1"},
+	} {
+		definitions[*arn(td.family, td.rev)] = &ecs.TaskDefinition{
+			Family:            aws.String(td.family),
+			Revision:          aws.Int64(td.rev),
+			Status:            aws.String(td.status),
+			TaskDefinitionArn: arn(td.family, td.rev),
+			RegisteredAt:      aws.Time(registered.Add(time.Duration(td.rev) * time.Hour)),
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				{Name: aws.String("datadog-agent"), Image: aws.String("datadog/agent:7")},
+				{Name: aws.String("api"), Image: aws.String("registry.example.com/testnut-api:" + td.tag)},
+			},
+		}
+	}
+
+	m := mocks.NewMockECSAPI(ctrl)
+	m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{{TaskDefinition: arn("test-api", 2)}},
+	}, nil).Times(1)
+	m.EXPECT().DescribeTaskDefinition(gomock.Any()).DoAndReturn(func(in *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+		return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: definitions[*in.TaskDefinition]}, nil
+	}).AnyTimes()
+	m.EXPECT().ListTaskDefinitions(gomock.Any()).DoAndReturn(func(in *ecs.ListTaskDefinitionsInput) (*ecs.ListTaskDefinitionsOutput, error) {
+		if *in.FamilyPrefix != "test-api" {
+			t.Errorf("ListTaskDefinitions() family prefix = %s", *in.FamilyPrefix)
+		}
+
+		if *in.Status == ecs.TaskDefinitionStatusInactive {
+			return &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: []*string{arn("test-api", 1)}}, nil
+		}
+
+		return &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: []*string{
+			arn("test-api-worker", 1), arn("test-api", 3), arn("test-api", 2),
+		}}, nil
+	}).Times(2)
+
+	e := &Manager{
+		Project: &config.Project{
+			AWSClient: config.NewAWSClient(config.WithECSClient(m)),
+		},
+		App: &config.Ecs{
+			Name:        "api",
+			Cluster:     "test-test",
+			ServiceName: "test-api",
+			Timeout:     300,
+		},
+	}
+
+	got, err := e.Revisions(10)
+	if err != nil {
+		t.Fatalf("Revisions() error = %v", err)
+	}
+
+	want := []Revision{
+		{Family: "test-api", Number: 3, Status: "ACTIVE", Image: "registry.example.com/testnut-api:c3", RegisteredAt: registered.Add(3 * time.Hour)},
+		{Family: "test-api", Number: 2, Status: "ACTIVE", Image: "registry.example.com/testnut-api:b2", RegisteredAt: registered.Add(2 * time.Hour), Current: true},
+		{Family: "test-api", Number: 1, Status: "INACTIVE", Image: "registry.example.com/testnut-api:a1", RegisteredAt: registered.Add(time.Hour)},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Revisions() = %+v, want %+v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Revisions()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got[0].Tag() != ":c3" {
+		t.Errorf("Tag() = %s, want :c3", got[0].Tag())
+	}
+}
+
+func TestManager_redeployLocal_serviceName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	current := "arn:aws:ecs:us-east-1:123456789012:task-definition/api-family:3"
+	target := "arn:aws:ecs:us-east-1:123456789012:task-definition/api-family:2"
+
+	m := mocks.NewMockECSAPI(ctrl)
+	m.EXPECT().DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  aws.String("test-test"),
+		Services: aws.StringSlice([]string{"api-service"}),
+	}).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{{TaskDefinition: aws.String(current)}},
+	}, nil).Times(1)
+	m.EXPECT().DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String("api-family:2"),
+	}).Return(&ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{Family: aws.String("api-family"), Revision: aws.Int64(2), TaskDefinitionArn: aws.String(target)},
+	}, nil)
+	m.EXPECT().UpdateService(&ecs.UpdateServiceInput{
+		Service:            aws.String("api-service"),
+		Cluster:            aws.String("test-test"),
+		TaskDefinition:     aws.String(target),
+		ForceNewDeployment: aws.Bool(true),
+	}).Return(&ecs.UpdateServiceOutput{Service: &ecs.Service{}}, nil)
+	m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{{
+			TaskDefinition: aws.String(target),
+			Deployments: []*ecs.Deployment{{
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String(target),
+				RolloutState:   aws.String(ecs.DeploymentRolloutStateCompleted),
+			}},
+		}},
+	}, nil)
+
+	e := &Manager{
+		Project: &config.Project{
+			Env:       "test",
+			AWSClient: config.NewAWSClient(config.WithECSClient(m)),
+		},
+		App: &config.Ecs{
+			Name:                   "api",
+			Cluster:                "test-test",
+			ServiceName:            "api-service",
+			Timeout:                300,
+			TaskDefinitionRevision: "2",
+		},
+	}
+
+	if err := e.redeployLocal(io.Discard); err != nil {
+		t.Fatalf("redeployLocal() error = %v", err)
+	}
+}
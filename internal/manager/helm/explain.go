@@ -0,0 +1,37 @@
+package helm
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/hazelops/ize/internal/config"
+)
+
+func (e *Manager) Explain() error {
+	e.prepare()
+
+	args, err := e.upgradeArgs()
+	if err != nil {
+		return err
+	}
+
+	return e.Project.Generate(upHelmAppTmpl, template.FuncMap{
+		"app": func() config.Helm {
+			return *e.App
+		},
+		"args": func() string {
+			return strings.Join(args, " \\\n\t")
+		},
+	})
+}
+
+var upHelmAppTmpl = `
+# Change to the dir
+cd {{app.Path}}
+
+# Deploy helm chart
+{{- if app.AwsProfile}}
+AWS_PROFILE={{app.AwsProfile}} \
+{{- end}}
+helm {{args}}
+`
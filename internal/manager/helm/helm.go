@@ -1,18 +1,22 @@
 package helm
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"github.com/hazelops/ize/pkg/term"
 	"io"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/hazelops/ize/pkg/term"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/docker/docker/api/types"
@@ -58,6 +62,24 @@ func (e *Manager) prepare() {
 	if e.App.Timeout == 0 {
 		e.App.Timeout = 300
 	}
+
+	if len(e.App.HelmRelease) == 0 {
+		e.App.HelmRelease = e.App.Name
+	}
+
+	if len(e.App.ChartPath) == 0 {
+		e.App.ChartPath = filepath.Join(e.App.Path, "helm", e.App.Name)
+	} else if !filepath.IsAbs(e.App.ChartPath) && !strings.Contains(e.App.ChartPath, "://") {
+		e.App.ChartPath = filepath.Join(e.App.Path, e.App.ChartPath)
+	}
+
+	if len(e.App.Kubeconfig) != 0 && !filepath.IsAbs(e.App.Kubeconfig) {
+		e.App.Kubeconfig = filepath.Join(e.Project.RootDir, e.App.Kubeconfig)
+	}
+
+	if len(e.App.AwsProfile) == 0 {
+		e.App.AwsProfile = e.Project.AwsProfile
+	}
 }
 
 // Deploy deploys app container to helm via helm deploy
@@ -99,18 +121,8 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 	s := sg.Add("%s: deploying app container...", e.App.Name)
 	defer func() { s.Abort(); time.Sleep(50 * time.Millisecond) }()
 
-	if e.App.Image == "" {
-		e.App.Image = fmt.Sprintf("%s/%s:%s",
-			e.App.DockerRegistry,
-			fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name),
-			fmt.Sprintf("%s-%s", e.Project.Env, "latest"))
-	}
-
 	if e.Project.PreferRuntime == "native" {
-
-		//err := e.deployLocal(s.TermOutput())
-		pterm.SetDefaultOutput(os.Stdout)
-		s = sg.Add("%s: deploying app [run helm deploy]...", e.App.Name)
+		s.Update("%s: deploying app [run helm upgrade]...", e.App.Name)
 		err := e.runDeploy(s.TermOutput())
 
 		if err != nil {
@@ -127,34 +139,130 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 	return nil
 }
 
-func (helm *Manager) runDeploy(w io.Writer) error {
-
-	// TODO build image name as a part of helm manager
-	logrus.Debugf(helm.Project.Namespace)
-	helmImageName := fmt.Sprintf("%s/%s-%s", helm.App.DockerRegistry, helm.Project.Namespace, helm.App.Name)
-	helmChartDir := fmt.Sprintf("%s", path.Join(helm.App.Path, "helm/api"))
-
-	var command string
-	namespace := fmt.Sprintf("%s-%s", helm.Project.Env, helm.Project.Namespace)
-	command = fmt.Sprintf(
-		`AWS_PROFILE="localstack-user" KUBECONFIG=/Users/dmitry/.kube/metameetings-local \
-		 helm upgrade --atomic --install --namespace "%s" "%s" %s \
-			--set image.repository=%s \
-			--set image.tag="%s"
-		`, namespace, helm.App.Name, helmChartDir, helmImageName, helm.Project.Tag)
-
-	//if sls.App.Force {
-	//	command += ` \
-	//	--force`
-	//}
+func (e *Manager) runDeploy(w io.Writer) error {
+	args, err := e.upgradeArgs()
+	if err != nil {
+		return err
+	}
+
+	return e.runHelm(w, args)
+}
+
+// upgradeArgs returns the arguments of the helm upgrade --install command
+// that deploys the app.
+func (e *Manager) upgradeArgs() ([]string, error) {
+	repository, tag := e.image()
+
+	args := []string{
+		"upgrade", "--install", "--atomic",
+		e.App.HelmRelease, e.App.ChartPath,
+		"--namespace", e.App.Namespace,
+		"--create-namespace",
+		"--timeout", fmt.Sprintf("%ds", e.App.Timeout),
+	}
 
-	logrus.SetOutput(w)
-	logrus.Debugf("command: %s", command)
+	valuesFiles, err := e.valuesFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range valuesFiles {
+		args = append(args, "--values", f)
+	}
+
+	args = append(args,
+		"--set", fmt.Sprintf("image.repository=%s", repository),
+		"--set", fmt.Sprintf("image.tag=%s", tag),
+	)
 
-	cmd := exec.Command("bash", "-c", command)
+	for _, v := range e.App.Set {
+		args = append(args, "--set", v)
+	}
+
+	if e.App.Force {
+		args = append(args, "--force")
+	}
+
+	return append(args, e.kubeArgs()...), nil
+}
+
+// image returns the image repository and tag passed to the chart.
+func (e *Manager) image() (string, string) {
+	if len(e.App.Image) == 0 {
+		return fmt.Sprintf("%s/%s-%s", e.App.DockerRegistry, e.Project.Namespace, e.App.Name), e.Project.Tag
+	}
+
+	i := strings.LastIndex(e.App.Image, ":")
+	if i == -1 || strings.Contains(e.App.Image[i:], "/") {
+		return e.App.Image, e.Project.Tag
+	}
+
+	return e.App.Image[:i], e.App.Image[i+1:]
+}
+
+// valuesFiles returns the values files of the app. The paths may use the
+// fields of the project, like {{.Env}}, and are relative to the app path.
+// When no values files are set, values-<env>.yaml from the chart directory
+// is used if it exists.
+func (e *Manager) valuesFiles() ([]string, error) {
+	if len(e.App.ValuesFiles) == 0 {
+		f := filepath.Join(e.App.ChartPath, fmt.Sprintf("values-%s.yaml", e.Project.Env))
+		if _, err := os.Stat(f); err == nil {
+			return []string{f}, nil
+		}
+
+		return nil, nil
+	}
+
+	var files []string
+	for _, f := range e.App.ValuesFiles {
+		t, err := template.New("values").Parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse values file path %s: %w", f, err)
+		}
+
+		var buf bytes.Buffer
+		err = t.Execute(&buf, e.Project)
+		if err != nil {
+			return nil, fmt.Errorf("can't render values file path %s: %w", f, err)
+		}
+
+		f = buf.String()
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(e.App.Path, f)
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+func (e *Manager) kubeArgs() []string {
+	var args []string
+
+	if len(e.App.KubeContext) != 0 {
+		args = append(args, "--kube-context", e.App.KubeContext)
+	}
+
+	if len(e.App.Kubeconfig) != 0 {
+		args = append(args, "--kubeconfig", e.App.Kubeconfig)
+	}
+
+	return args
+}
+
+func (e *Manager) runHelm(w io.Writer, args []string) error {
+	cmd := exec.Command("helm", args...)
+	cmd.Env = os.Environ()
+	if len(e.App.AwsProfile) != 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_PROFILE=%s", e.App.AwsProfile))
+	}
+
+	logrus.Debugf("command: helm %s", strings.Join(args, " "))
 
 	return term.New(
-		term.WithDir(helm.App.Path),
+		term.WithDir(e.App.Path),
 		term.WithStdout(w),
 		term.WithStderr(w),
 	).InteractiveRun(cmd)
@@ -312,58 +420,34 @@ func (e *Manager) Build(ui terminal.UI) error {
 	return nil
 }
 
-//func definitionsToBulletItems(definitions *Helm.ListTaskDefinitionsOutput) []pterm.BulletListItem {
-//	var items []pterm.BulletListItem
-//	//for _, arn := range definitions.TaskDefinitionArns {
-//	//	items = append(items, pterm.BulletListItem{Level: 0, Text: *arn})
-//	//}
-//
-//	return items
-//}
-
 func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
+	e.prepare()
+
 	sg := ui.StepGroup()
 	defer sg.Wait()
 
-	s := sg.Add("%s: destroying Helm application...", e.App.Name)
+	s := sg.Add("%s: destroying Helm release %s...", e.App.Name, e.App.HelmRelease)
 	defer func() { s.Abort(); time.Sleep(time.Millisecond * 200) }()
 
-	//name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
-	//
-	//svc := e.Project.AWSClient.helmClient
-	//
-	//definitions, err := svc.ListTaskDefinitions(&helm.ListTaskDefinitionsInput{
-	//	FamilyPrefix: &name,
-	//	Sort:         aws.String(helm.SortOrderDesc),
-	//})
-	//if err != nil {
-	//	return fmt.Errorf("can't get list task definitions of '%s': %v", name, err)
-	//}
-	//
-	//if !autoApprove {
-	//	pterm.SetDefaultOutput(s.TermOutput())
-	//
-	//	pterm.Printfln("this will destroy the following:")
-	//	pterm.DefaultBulletList.WithItems(definitionsToBulletItems(definitions)).Render()
-	//
-	//	isContinue, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Continue?").Show()
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	if !isContinue {
-	//		return fmt.Errorf("destroying was canceled")
-	//	}
-	//}
-	//
-	//for _, tda := range definitions.TaskDefinitionArns {
-	//	_, err := e.Project.AWSClient.helmClient.DeregisterTaskDefinition(&helm.DeregisterTaskDefinitionInput{
-	//		TaskDefinition: tda,
-	//	})
-	//	if err != nil {
-	//		return fmt.Errorf("can't deregister task definition '%s': %v", *tda, err)
-	//	}
-	//}
+	if !autoApprove {
+		pterm.Fprintln(s.TermOutput(), fmt.Sprintf("this will uninstall the release %s from the namespace %s", e.App.HelmRelease, e.App.Namespace))
+
+		isContinue, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Continue?").Show()
+		if err != nil {
+			return err
+		}
+
+		if !isContinue {
+			return fmt.Errorf("destroying was canceled")
+		}
+	}
+
+	args := append([]string{"uninstall", e.App.HelmRelease, "--namespace", e.App.Namespace, "--wait"}, e.kubeArgs()...)
+
+	err := e.runHelm(s.TermOutput(), args)
+	if err != nil {
+		return fmt.Errorf("can't uninstall release %s: %w", e.App.HelmRelease, err)
+	}
 
 	s.Done()
 	s = sg.Add("%s: destroying completed!", e.App.Name)
@@ -371,9 +455,7 @@ func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
 
 	return nil
 }
+
 func (e *Manager) Redeploy(ui terminal.UI) error {
 	return nil
 }
-func (e *Manager) Explain() error {
-	return nil
-}
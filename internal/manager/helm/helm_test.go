@@ -0,0 +1,182 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+// fakeHelm puts a helm script into PATH that records its arguments and
+// returns the path of the file they are written to.
+func fakeHelm(t *testing.T, exitCode string) string {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "helm.args")
+
+	script := "#!/bin/bash\necho \"AWS_PROFILE=$AWS_PROFILE $@\" >> " + out + "\nexit " + exitCode + "\n"
+	err := os.WriteFile(filepath.Join(dir, "helm"), []byte(script), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return out
+}
+
+func testProject(root string) *config.Project {
+	return &config.Project{
+		Env:            "test",
+		Namespace:      "testnut",
+		Tag:            "abc123",
+		AwsProfile:     "default",
+		DockerRegistry: "registry.example.com",
+		PreferRuntime:  "native",
+		RootDir:        root,
+	}
+}
+
+func TestManager_Deploy(t *testing.T) {
+	tests := []struct {
+		name     string
+		app      config.Helm
+		files    []string
+		exitCode string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			app:      config.Helm{Name: "api", Path: "apps/api"},
+			exitCode: "0",
+			want: "AWS_PROFILE=default upgrade --install --atomic api {root}/apps/api/helm/api " +
+				"--namespace test-testnut --create-namespace --timeout 300s " +
+				"--set image.repository=registry.example.com/testnut-api --set image.tag=abc123",
+		},
+		{
+			name:     "values file of the env",
+			app:      config.Helm{Name: "api", Path: "apps/api"},
+			files:    []string{"apps/api/helm/api/values-test.yaml"},
+			exitCode: "0",
+			want: "AWS_PROFILE=default upgrade --install --atomic api {root}/apps/api/helm/api " +
+				"--namespace test-testnut --create-namespace --timeout 300s " +
+				"--values {root}/apps/api/helm/api/values-test.yaml " +
+				"--set image.repository=registry.example.com/testnut-api --set image.tag=abc123",
+		},
+		{
+			name: "all options",
+			app: config.Helm{
+				Name:        "api",
+				Path:        "apps/api",
+				HelmRelease: "api-release",
+				Namespace:   "api",
+				ChartPath:   "chart",
+				ValuesFiles: []string{"values/common.yaml", "values/{{.Env}}.yaml"},
+				Set:         []string{"replicaCount=2", "ingress.enabled=true"},
+				KubeContext: "kind-local",
+				Kubeconfig:  ".kube/config",
+				Image:       "nginx:1.25",
+				Timeout:     60,
+				AwsProfile:  "k8s",
+				Force:       true,
+			},
+			exitCode: "0",
+			want: "AWS_PROFILE=k8s upgrade --install --atomic api-release {root}/apps/api/chart " +
+				"--namespace api --create-namespace --timeout 60s " +
+				"--values {root}/apps/api/values/common.yaml --values {root}/apps/api/values/test.yaml " +
+				"--set image.repository=nginx --set image.tag=1.25 " +
+				"--set replicaCount=2 --set ingress.enabled=true --force " +
+				"--kube-context kind-local --kubeconfig {root}/.kube/config",
+		},
+		{
+			name:     "helm fails",
+			app:      config.Helm{Name: "api", Path: "apps/api"},
+			exitCode: "1",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := fakeHelm(t, tt.exitCode)
+			root := t.TempDir()
+
+			err := os.MkdirAll(filepath.Join(root, "apps", "api"), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range tt.files {
+				err := os.MkdirAll(filepath.Dir(filepath.Join(root, f)), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(filepath.Join(root, f), nil, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			app := tt.app
+			h := &Manager{
+				Project: testProject(root),
+				App:     &app,
+			}
+
+			err = h.Deploy(terminal.ConsoleUI(context.TODO(), true))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deploy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := strings.ReplaceAll(tt.want, "{root}", root)
+			if strings.TrimSpace(string(got)) != want {
+				t.Errorf("Deploy() ran helm with\n%s\nwant\n%s", strings.TrimSpace(string(got)), want)
+			}
+		})
+	}
+}
+
+func TestManager_Destroy(t *testing.T) {
+	out := fakeHelm(t, "0")
+	root := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(root, "apps", "api"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Manager{
+		Project: testProject(root),
+		App: &config.Helm{
+			Name:        "api",
+			Path:        "apps/api",
+			KubeContext: "kind-local",
+		},
+	}
+
+	err = h.Destroy(terminal.ConsoleUI(context.TODO(), true), true)
+	if err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "AWS_PROFILE=default uninstall api --namespace test-testnut --wait --kube-context kind-local"
+	if strings.TrimSpace(string(got)) != want {
+		t.Errorf("Destroy() ran helm with\n%s\nwant\n%s", strings.TrimSpace(string(got)), want)
+	}
+}
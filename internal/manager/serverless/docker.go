@@ -22,40 +22,11 @@ func (sls *Manager) deployWithDocker(s terminal.Step) error {
 		return err
 	}
 
-	image := "node:" + sls.App.NodeVersion
-
-	s.Update("%s: checking for Docker image: %s", sls.App.Name, image)
-
-	imageRef, err := reference.ParseNormalizedNamed(image)
-	if err != nil {
-		return fmt.Errorf("error parsing Docker image: %s", err)
-	}
-
-	imageList, err := cli.ImageList(context.Background(), types.ImageListOptions{
-		Filters: filters.NewArgs(filters.KeyValuePair{
-			Key:   "reference",
-			Value: reference.FamiliarString(imageRef),
-		}),
-	})
+	err = sls.pullNodeImage(cli, s)
 	if err != nil {
 		return err
 	}
 
-	if len(imageList) == 0 {
-		s.Update("%s: pulling image: %s", sls.App.Name, image)
-
-		resp, err := cli.ImagePull(context.Background(), reference.FamiliarString(imageRef), types.ImagePullOptions{})
-		if err != nil {
-			return err
-		}
-		defer resp.Close()
-
-		err = jsonmessage.DisplayJSONMessagesStream(resp, s.TermOutput(), os.Stdout.Fd(), true, nil)
-		if err != nil {
-			return fmt.Errorf("unable to stream pull logs to the terminal: %s", err)
-		}
-	}
-
 	s.Update("%s: downloading npm modules...", sls.App.Name)
 
 	err = sls.npm(cli, []string{"npm", "install", "--save-dev"}, s)
@@ -107,6 +78,33 @@ func (sls *Manager) removeWithDocker(s terminal.Step) error {
 		return err
 	}
 
+	err = sls.pullNodeImage(cli, s)
+	if err != nil {
+		return err
+	}
+
+	s.Done()
+	s.Update("%s: destroying app...", sls.App.Name)
+
+	err = sls.serverless(cli, []string{
+		"remove",
+		"--config", sls.App.File,
+		"--service", sls.App.Name,
+		"--verbose",
+		"--region", sls.App.AwsRegion,
+		"--stage", sls.Project.Env,
+		"--profile", sls.App.AwsProfile,
+	}, s)
+	if err != nil {
+		s.Abort()
+		return err
+	}
+
+	return nil
+}
+
+// pullNodeImage pulls the node image of the app unless it's already present.
+func (sls *Manager) pullNodeImage(cli *client.Client, s terminal.Step) error {
 	image := "node:" + sls.App.NodeVersion
 
 	s.Update("%s: checking for Docker image: %s", sls.App.Name, image)
@@ -141,13 +139,27 @@ func (sls *Manager) removeWithDocker(s terminal.Step) error {
 		}
 	}
 
-	s.Done()
-	s.Update("%s: destroying app...", sls.App.Name)
+	return nil
+}
+
+func (sls *Manager) rollbackWithDocker(s terminal.Step) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return err
+	}
+
+	err = sls.pullNodeImage(cli, s)
+	if err != nil {
+		return err
+	}
+
+	s.Update("%s: rolling back app to %s...", sls.App.Name, sls.App.RollbackTimestamp)
 
 	err = sls.serverless(cli, []string{
-		"remove",
+		"rollback",
 		"--config", sls.App.File,
 		"--service", sls.App.Name,
+		"--timestamp", sls.App.RollbackTimestamp,
 		"--verbose",
 		"--region", sls.App.AwsRegion,
 		"--stage", sls.Project.Env,
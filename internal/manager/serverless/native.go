@@ -281,6 +281,77 @@ func (sls *Manager) runRemove(w io.Writer) error {
 	return nil
 }
 
+func (sls *Manager) runRollback(w io.Writer) error {
+	nvmDir, err := sls.installNvm()
+	if err != nil {
+		return err
+	}
+
+	var command string
+
+	// SLS v3 has breaking changes in syntax
+	if sls.App.ServerlessVersion == "3" {
+		command = fmt.Sprintf(
+			`source %s/nvm.sh && \
+				nvm use %s && \
+				npx serverless rollback \
+				--config=%s \
+				--param="service=%s" \
+				--timestamp=%s \
+				--region=%s \
+				--aws-profile=%s \
+				--stage=%s \
+				--verbose`,
+			nvmDir, sls.App.NodeVersion, sls.App.File,
+			sls.App.Name, sls.App.RollbackTimestamp, sls.App.AwsRegion,
+			sls.App.AwsProfile, sls.Project.Env)
+	} else {
+		command = fmt.Sprintf(
+			`source %s/nvm.sh && \
+				nvm use %s && \
+				npx serverless rollback \
+				--config %s \
+				--service %s \
+				--timestamp %s \
+				--verbose \
+				--region %s \
+				--aws-profile %s \
+				--stage %s`,
+			nvmDir, sls.App.NodeVersion, sls.App.File,
+			sls.App.Name, sls.App.RollbackTimestamp, sls.App.AwsRegion,
+			sls.App.AwsProfile, sls.Project.Env)
+	}
+
+	if sls.App.UseYarn {
+		command = npmToYarn(command)
+	}
+
+	logrus.SetOutput(w)
+	logrus.Debugf("command: %s", command)
+
+	cmd := exec.Command("bash", "-c", command)
+
+	// Capture stderr in a buffer
+	var stderr bytes.Buffer
+	var stdout bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.Stdout = &stdout
+
+	t := term.New(
+		term.WithDir(sls.App.Path),
+		term.WithStdout(w),
+		term.WithStderr(&stderr),
+	)
+
+	err = t.InteractiveRun(cmd)
+	if err != nil {
+		// Return the error along with stderr output
+		return fmt.Errorf("command failed with error: %w, %s %s", err, stderr.String(), stdout.String())
+	}
+
+	return nil
+}
+
 func (sls *Manager) runCreateDomain(w io.Writer) error {
 	nvmDir, err := sls.installNvm()
 	if err != nil {
@@ -419,7 +490,7 @@ func (sls *Manager) installNvm() (string, error) {
 	cmd.Dir = sls.Project.RootDir
 	err = cmd.Run()
 	if err != nil {
-		logrus.Debugf("Error installing nvm:", err)
+		logrus.Debugf("Error installing nvm: %s", err)
 		return "", err
 	}
 
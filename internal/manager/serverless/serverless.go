@@ -201,6 +201,53 @@ func (sls *Manager) Build(ui terminal.UI) error {
 	return nil
 }
 
+// Redeploy rolls the app back to the deployment with RollbackTimestamp via
+// serverless rollback. Without the timestamp there is nothing to redeploy.
 func (sls *Manager) Redeploy(ui terminal.UI) error {
+	if len(sls.App.RollbackTimestamp) == 0 {
+		return nil
+	}
+
+	sls.prepare()
+
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	s := sg.Add("%s: rolling back app to %s...", sls.App.Name, sls.App.RollbackTimestamp)
+	defer func() { s.Abort(); time.Sleep(time.Millisecond * 200) }()
+
+	switch sls.Project.PreferRuntime {
+	case "native":
+		s.Update("%s: rolling back app [run nvm use]...", sls.App.Name)
+
+		err := sls.runNvm(s.TermOutput())
+		if err != nil {
+			return fmt.Errorf("can't run nvm: %w", err)
+		}
+
+		s.Done()
+		s = sg.Add("%s: rolling back app [run dependency install]...", sls.App.Name)
+		err = sls.runNpmInstall(s.TermOutput())
+		if err != nil {
+			return fmt.Errorf("can't run dependency install: %w", err)
+		}
+
+		s.Done()
+		s = sg.Add("%s: rolling back app [run serverless rollback]...", sls.App.Name)
+		err = sls.runRollback(s.TermOutput())
+		if err != nil {
+			return fmt.Errorf("can't run serverless rollback: %w", err)
+		}
+	case "docker":
+		err := sls.rollbackWithDocker(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Done()
+	s = sg.Add("%s: rollback completed!", sls.App.Name)
+	s.Done()
+
 	return nil
 }
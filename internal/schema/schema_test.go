@@ -10,7 +10,7 @@ var valid = map[string]interface{}{
 	"aws_region":  "us-east-1",
 	"ecs": map[string]interface{}{
 		"goblin":  map[string]interface{}{"cluster": "testnut-nutcorp", "skip_deploy": true, "timeout": 600},
-		"squibby": map[string]interface{}{"timeout": 1200, "unsafe": true}},
+		"squibby": map[string]interface{}{"timeout": 1200, "unsafe": true, "secrets_from": "ssm"}},
 	"env":            "testnut",
 	"env_dir":        "/home/testnut/example/.ize/env/testnut",
 	"home":           "/home/testnut",
@@ -71,6 +71,37 @@ var validDeprecated = map[string]interface{}{
 
 var empty = map[string]interface{}{}
 
+// withBackend returns the valid config with the backend of the infra stack
+func withBackend(backend map[string]interface{}) map[string]interface{} {
+	return withInfra(map[string]interface{}{"aws_profile": "testnut", "backend": backend})
+}
+
+// withInfra returns the valid config with the infra stack
+func withInfra(infra map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{}
+	for k, v := range valid {
+		config[k] = v
+	}
+
+	config["terraform"] = map[string]interface{}{
+		"infra": infra,
+	}
+
+	return config
+}
+
+// withSecrets returns the valid config with the encryption of secrets files
+func withSecrets(secrets map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{}
+	for k, v := range valid {
+		config[k] = v
+	}
+
+	config["secrets"] = secrets
+
+	return config
+}
+
 func TestValidate(t *testing.T) {
 	type args struct {
 		config map[string]interface{}
@@ -85,6 +116,21 @@ func TestValidate(t *testing.T) {
 		{name: "invalid parameter", args: args{config: invalidParameter}, wantErr: true},
 		{name: "invalid type", args: args{config: invalidType}, wantErr: true},
 		{name: "empty", args: args{config: map[string]interface{}{}}, wantErr: true},
+		{name: "valid s3 backend", args: args{config: withBackend(map[string]interface{}{"bucket": "testnut-tf-state", "use_lockfile": true})}, wantErr: false},
+		{name: "valid local backend", args: args{config: withBackend(map[string]interface{}{"type": "local", "path": "terraform.tfstate"})}, wantErr: false},
+		{name: "valid http backend", args: args{config: withBackend(map[string]interface{}{"type": "http", "address": "https://state.example.com/infra"})}, wantErr: false},
+		{name: "valid pg backend", args: args{config: withBackend(map[string]interface{}{"type": "pg", "schema_name": "testnut"})}, wantErr: false},
+		{name: "http backend without address", args: args{config: withBackend(map[string]interface{}{"type": "http"})}, wantErr: true},
+		{name: "option of other backend", args: args{config: withBackend(map[string]interface{}{"type": "local", "bucket": "testnut-tf-state"})}, wantErr: true},
+		{name: "unknown backend", args: args{config: withBackend(map[string]interface{}{"type": "gcs"})}, wantErr: true},
+		{name: "valid vars", args: args{config: withInfra(map[string]interface{}{"vars": map[string]interface{}{"instance_count": 2, "domain": "{{.Env}}.examples.ize.sh"}, "var_files": []interface{}{"common.tfvars"}})}, wantErr: false},
+		{name: "valid inputs", args: args{config: withInfra(map[string]interface{}{"inputs": map[string]interface{}{"vpc_id": "vpc.vpc_id"}})}, wantErr: false},
+		{name: "invalid input", args: args{config: withInfra(map[string]interface{}{"inputs": map[string]interface{}{"vpc_id": "vpc_id"}})}, wantErr: true},
+		{name: "valid workspace layout", args: args{config: withInfra(map[string]interface{}{"layout": "workspace", "path": "terraform/infra", "backend": map[string]interface{}{"workspace_key_prefix": "infra"}})}, wantErr: false},
+		{name: "invalid layout", args: args{config: withInfra(map[string]interface{}{"layout": "directory"})}, wantErr: true},
+		{name: "invalid var files", args: args{config: withInfra(map[string]interface{}{"var_files": "common.tfvars"})}, wantErr: true},
+		{name: "valid secrets", args: args{config: withSecrets(map[string]interface{}{"age": []interface{}{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}, "kms": []interface{}{"arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"}})}, wantErr: false},
+		{name: "invalid secrets kms key", args: args{config: withSecrets(map[string]interface{}{"kms": []interface{}{"alias/secrets"}})}, wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
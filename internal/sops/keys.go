@@ -0,0 +1,172 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const (
+	// AgeKeyEnv holds age identities, one per line.
+	AgeKeyEnv = "SOPS_AGE_KEY"
+	// AgeKeyFileEnv is the path of an age identities file. It defaults to
+	// sops/age/keys.txt in the user config dir like sops does.
+	AgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+)
+
+func encryptAge(dataKey []byte, recipient string) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", fmt.Errorf("can't parse age recipient %s: %w", recipient, err)
+	}
+
+	var b bytes.Buffer
+
+	aw := armor.NewWriter(&b)
+
+	w, err := age.Encrypt(aw, r)
+	if err != nil {
+		return "", fmt.Errorf("can't encrypt data key with age: %w", err)
+	}
+
+	if _, err := w.Write(dataKey); err != nil {
+		return "", fmt.Errorf("can't encrypt data key with age: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("can't encrypt data key with age: %w", err)
+	}
+
+	if err := aw.Close(); err != nil {
+		return "", fmt.Errorf("can't encrypt data key with age: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+func encryptKMS(api kmsiface.KMSAPI, dataKey []byte, arn string) (string, error) {
+	if api == nil {
+		return "", fmt.Errorf("can't encrypt data key with %s: KMS client is not configured", arn)
+	}
+
+	out, err := api.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(arn),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't encrypt data key with %s: %w", arn, err)
+	}
+
+	return base64.StdEncoding.EncodeToString(out.CiphertextBlob), nil
+}
+
+// decryptDataKey decrypts the data key with the first key that works, age
+// keys are tried first as they don't need AWS access.
+func decryptDataKey(m metadata, api kmsiface.KMSAPI) ([]byte, error) {
+	var errs []string
+
+	if len(m.Age) != 0 {
+		identities, err := ageIdentities()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		for _, k := range m.Age {
+			if len(identities) == 0 {
+				break
+			}
+
+			dataKey, err := decryptAge(k.Enc, identities)
+			if err == nil {
+				return dataKey, nil
+			}
+			errs = append(errs, fmt.Sprintf("age %s: %s", k.Recipient, err))
+		}
+	}
+
+	for _, k := range m.KMS {
+		if api == nil {
+			errs = append(errs, fmt.Sprintf("kms %s: KMS client is not configured", k.ARN))
+			continue
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(k.Enc)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("kms %s: %s", k.ARN, err))
+			continue
+		}
+
+		out, err := api.Decrypt(&kms.DecryptInput{
+			CiphertextBlob: blob,
+			KeyId:          aws.String(k.ARN),
+		})
+		if err == nil {
+			return out.Plaintext, nil
+		}
+		errs = append(errs, fmt.Sprintf("kms %s: %s", k.ARN, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("can't decrypt data key: no age or KMS keys in sops metadata")
+	}
+
+	return nil, fmt.Errorf("can't decrypt data key:\n  - %s", strings.Join(errs, "\n  - "))
+}
+
+func decryptAge(enc string, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+// ageIdentities returns the identities of SOPS_AGE_KEY and of the keys file.
+func ageIdentities() ([]age.Identity, error) {
+	var identities []age.Identity
+
+	if key := os.Getenv(AgeKeyEnv); len(key) != 0 {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %s: %w", AgeKeyEnv, err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	path := os.Getenv(AgeKeyFileEnv)
+	if len(path) == 0 {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return identities, nil
+		}
+		path = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && len(identities) != 0 {
+		return identities, nil
+	}
+	if err != nil {
+		return identities, fmt.Errorf("can't read age identities: %w", err)
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return identities, fmt.Errorf("can't parse %s: %w", path, err)
+	}
+
+	return append(identities, ids...), nil
+}
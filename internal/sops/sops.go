@@ -0,0 +1,399 @@
+// Package sops encrypts flat secrets files per value in the JSON format of
+// SOPS (https://github.com/getsops/sops), so the files can be read by sops
+// too. The data key of a file is encrypted with age recipients and/or AWS KMS
+// keys.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const (
+	// Version is the version of sops the metadata is written for.
+	Version = "3.7.3"
+	// UnencryptedSuffix is the suffix of keys whose values are not
+	// encrypted.
+	UnencryptedSuffix = "_unencrypted"
+
+	metadataKey = "sops"
+	dataKeySize = 32
+	ivSize      = 32
+)
+
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// Keys are the keys the data key of a file is encrypted with.
+type Keys struct {
+	// Age are age recipients, e.g. age1...
+	Age []string
+	// KMS are ARNs of AWS KMS keys.
+	KMS []string
+}
+
+// Empty reports whether no keys are set.
+func (k Keys) Empty() bool {
+	return len(k.Age) == 0 && len(k.KMS) == 0
+}
+
+type metadata struct {
+	KMS               []kmsKey    `json:"kms"`
+	GCPKMS            interface{} `json:"gcp_kms"`
+	AzureKV           interface{} `json:"azure_kv"`
+	HCVault           interface{} `json:"hc_vault"`
+	Age               []ageKey    `json:"age"`
+	LastModified      string      `json:"lastmodified"`
+	MAC               string      `json:"mac"`
+	PGP               interface{} `json:"pgp"`
+	UnencryptedSuffix string      `json:"unencrypted_suffix"`
+	Version           string      `json:"version"`
+}
+
+type kmsKey struct {
+	ARN        string `json:"arn"`
+	CreatedAt  string `json:"created_at"`
+	Enc        string `json:"enc"`
+	AWSProfile string `json:"aws_profile"`
+}
+
+type ageKey struct {
+	Recipient string `json:"recipient"`
+	Enc       string `json:"enc"`
+}
+
+// IsEncrypted reports whether the data is a JSON object with sops metadata.
+func IsEncrypted(data []byte) bool {
+	var doc map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+
+	_, ok := doc[metadataKey]
+
+	return ok
+}
+
+// Encrypt encrypts every value with a new data key and returns the document
+// with sorted keys and sops metadata.
+func Encrypt(values map[string]string, keys Keys, kmsAPI kmsiface.KMSAPI) ([]byte, error) {
+	if keys.Empty() {
+		return nil, fmt.Errorf("at least one age recipient or KMS key must be set")
+	}
+
+	dataKey := make([]byte, dataKeySize)
+
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("can't generate data key: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	m := metadata{
+		LastModified:      now,
+		UnencryptedSuffix: UnencryptedSuffix,
+		Version:           Version,
+	}
+
+	for _, recipient := range keys.Age {
+		enc, err := encryptAge(dataKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		m.Age = append(m.Age, ageKey{Recipient: recipient, Enc: enc})
+	}
+
+	for _, arn := range keys.KMS {
+		enc, err := encryptKMS(kmsAPI, dataKey, arn)
+		if err != nil {
+			return nil, err
+		}
+		m.KMS = append(m.KMS, kmsKey{ARN: arn, CreatedAt: now, Enc: enc})
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if name == metadataKey {
+			return nil, fmt.Errorf("key %s is reserved for sops metadata", metadataKey)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := sha512.New()
+	encrypted := make([]string, 0, len(names))
+
+	for _, name := range names {
+		value := values[name]
+		mac.Write([]byte(value))
+
+		if suffixed(name, UnencryptedSuffix) {
+			encrypted = append(encrypted, value)
+			continue
+		}
+
+		enc, err := encryptValue(dataKey, value, "str", name+":")
+		if err != nil {
+			return nil, fmt.Errorf("can't encrypt %s: %w", name, err)
+		}
+		encrypted = append(encrypted, enc)
+	}
+
+	m.MAC, err = encryptValue(dataKey, strings.ToUpper(hex.EncodeToString(mac.Sum(nil))), "str", m.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("can't encrypt mac: %w", err)
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("{\n")
+
+	for i, name := range names {
+		fmt.Fprintf(&b, "  %s: %s,\n", marshal(name), marshal(encrypted[i]))
+	}
+
+	md, err := json.MarshalIndent(m, "  ", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "  %s: %s\n}\n", marshal(metadataKey), md)
+
+	return b.Bytes(), nil
+}
+
+// Decrypt decrypts the document and verifies its MAC. The data key is
+// decrypted with age identities of SOPS_AGE_KEY, SOPS_AGE_KEY_FILE or the
+// sops keys file, or with KMS.
+func Decrypt(data []byte, kmsAPI kmsiface.KMSAPI) (map[string]string, error) {
+	names, raw, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mdRaw, ok := raw[metadataKey]
+	if !ok {
+		return nil, fmt.Errorf("sops metadata not found")
+	}
+
+	var m metadata
+
+	err = json.Unmarshal(mdRaw, &m)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse sops metadata: %w", err)
+	}
+
+	dataKey, err := decryptDataKey(m, kmsAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	mac := sha512.New()
+
+	for _, name := range names {
+		if name == metadataKey {
+			continue
+		}
+
+		var v interface{}
+
+		d := json.NewDecoder(bytes.NewReader(raw[name]))
+		d.UseNumber()
+
+		err = d.Decode(&v)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %s: %w", name, err)
+		}
+
+		// sops hashes booleans as True and False
+		var value, macValue string
+
+		switch v := v.(type) {
+		case string:
+			value, macValue = v, v
+			if !suffixed(name, m.UnencryptedSuffix) {
+				plaintext, typ, err := decryptValue(dataKey, v, name+":")
+				if err != nil {
+					return nil, fmt.Errorf("can't decrypt %s: %w", name, err)
+				}
+
+				value, macValue = plaintext, plaintext
+				if typ == "bool" {
+					value = strings.ToLower(plaintext)
+				}
+			}
+		case json.Number:
+			value, macValue = v.String(), v.String()
+		case bool:
+			value, macValue = strconv.FormatBool(v), "False"
+			if v {
+				macValue = "True"
+			}
+		default:
+			return nil, fmt.Errorf("value of %s must be a string, number or boolean", name)
+		}
+
+		mac.Write([]byte(macValue))
+		values[name] = value
+	}
+
+	storedMAC, _, err := decryptValue(dataKey, m.MAC, m.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("can't decrypt mac: %w", err)
+	}
+
+	if storedMAC != strings.ToUpper(hex.EncodeToString(mac.Sum(nil))) {
+		return nil, fmt.Errorf("mac mismatch, the file was modified without sops or ize")
+	}
+
+	return values, nil
+}
+
+func suffixed(name string, suffix string) bool {
+	return len(suffix) != 0 && strings.HasSuffix(name, suffix)
+}
+
+// decodeObject returns the keys of the JSON object in the order of the
+// document, which is the order sops computes the MAC in.
+func decodeObject(data []byte) ([]string, map[string]json.RawMessage, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+
+	t, err := d.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("secrets must be a JSON object")
+	}
+
+	var names []string
+	raw := map[string]json.RawMessage{}
+
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := t.(string)
+
+		var value json.RawMessage
+
+		err = d.Decode(&value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		names = append(names, name)
+		raw[name] = value
+	}
+
+	return names, raw, nil
+}
+
+func marshal(s string) []byte {
+	var b bytes.Buffer
+
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	_ = e.Encode(s)
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// encryptValue encrypts the value with AES-256-GCM the way sops does: a 32
+// bytes IV, the path of the value as additional data and the tag stored
+// separately. Empty values are not encrypted.
+func encryptValue(key []byte, value string, typ string, additionalData string) (string, error) {
+	if len(value) == 0 {
+		return "", nil
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, ivSize)
+
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := out[:len(out)-gcm.Overhead()], out[len(out)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		typ,
+	), nil
+}
+
+// decryptValue returns the plaintext and the type of an encrypted value.
+func decryptValue(key []byte, value string, additionalData string) (string, string, error) {
+	if len(value) == 0 {
+		return "", "str", nil
+	}
+
+	match := encryptedValue.FindStringSubmatch(value)
+	if match == nil {
+		return "", "", fmt.Errorf("value is not encrypted by sops")
+	}
+
+	var parts [3][]byte
+
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return "", "", err
+		}
+		parts[i] = b
+	}
+
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	if len(iv) != ivSize {
+		return "", "", fmt.Errorf("invalid iv length %d", len(iv))
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(plaintext), match[4], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCMWithNonceSize(block, ivSize)
+}
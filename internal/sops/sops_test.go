@@ -0,0 +1,285 @@
+package sops
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+//go:generate mockgen -package=mocks -destination ../../pkg/mocks/mock_kms.go github.com/aws/aws-sdk-go/service/kms/kmsiface KMSAPI
+
+var testValues = map[string]string{
+	"API_KEY":          "secret",
+	"EMPTY":            "",
+	"MULTILINE":        "line 1\nline 2",
+	"PORT":             "8080",
+	"NAME_unencrypted": "squibby",
+}
+
+func newAgeKey(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(AgeKeyEnv, identity.String())
+	t.Setenv(AgeKeyFileEnv, t.TempDir()+"/keys.txt")
+
+	return identity
+}
+
+func TestEncryptDecrypt_age(t *testing.T) {
+	identity := newAgeKey(t)
+
+	data, err := Encrypt(testValues, Keys{Age: []string{identity.Recipient().String()}}, nil)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	if !IsEncrypted(data) {
+		t.Fatalf("IsEncrypted() = false")
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range testValues {
+		got := doc[name].(string)
+
+		switch {
+		case strings.HasSuffix(name, UnencryptedSuffix), len(value) == 0:
+			if got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		default:
+			match := encryptedValue.FindStringSubmatch(got)
+			if match == nil || match[4] != "str" {
+				t.Fatalf("%s = %q is not a sops value", name, got)
+			}
+
+			iv, _ := base64.StdEncoding.DecodeString(match[2])
+			if len(iv) != ivSize {
+				t.Errorf("iv of %s has %d bytes, want %d", name, len(iv), ivSize)
+			}
+		}
+	}
+
+	got, err := Decrypt(data, nil)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, testValues) {
+		t.Errorf("Decrypt() = %#v, want %#v", got, testValues)
+	}
+}
+
+func TestEncryptDecrypt_kms(t *testing.T) {
+	t.Setenv(AgeKeyEnv, "")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	arn := "arn:aws:kms:us-east-1:123456789012:key/test"
+	var dataKey []byte
+
+	m := mocks.NewMockKMSAPI(ctrl)
+	m.EXPECT().Encrypt(gomock.Any()).DoAndReturn(func(in *kms.EncryptInput) (*kms.EncryptOutput, error) {
+		if aws.StringValue(in.KeyId) != arn {
+			t.Errorf("Encrypt() key = %s, want %s", aws.StringValue(in.KeyId), arn)
+		}
+		dataKey = in.Plaintext
+		return &kms.EncryptOutput{CiphertextBlob: []byte("blob")}, nil
+	})
+	m.EXPECT().Decrypt(&kms.DecryptInput{
+		CiphertextBlob: []byte("blob"),
+		KeyId:          aws.String(arn),
+	}).DoAndReturn(func(in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+		return &kms.DecryptOutput{Plaintext: dataKey}, nil
+	})
+
+	data, err := Encrypt(testValues, Keys{KMS: []string{arn}}, m)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	got, err := Decrypt(data, m)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, testValues) {
+		t.Errorf("Decrypt() = %#v, want %#v", got, testValues)
+	}
+}
+
+func TestDecrypt_tampered(t *testing.T) {
+	identity := newAgeKey(t)
+
+	data, err := Encrypt(testValues, Keys{Age: []string{identity.Recipient().String()}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(string) string
+	}{
+		{
+			name: "unencrypted value changed",
+			tamper: func(s string) string {
+				return strings.Replace(s, `"squibby"`, `"goblin"`, 1)
+			},
+		},
+		{
+			name: "value moved to another key",
+			tamper: func(s string) string {
+				var doc map[string]interface{}
+				_ = json.Unmarshal([]byte(s), &doc)
+				return strings.Replace(s, doc["PORT"].(string), doc["API_KEY"].(string), 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt([]byte(tt.tamper(string(data))), nil)
+			if err == nil {
+				t.Errorf("Decrypt() of a tampered file succeeded")
+			}
+		})
+	}
+}
+
+func TestDecrypt_wrongIdentity(t *testing.T) {
+	identity := newAgeKey(t)
+
+	data, err := Encrypt(testValues, Keys{Age: []string{identity.Recipient().String()}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newAgeKey(t)
+
+	_, err = Decrypt(data, nil)
+	if err == nil {
+		t.Errorf("Decrypt() with a wrong identity succeeded")
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{data: `{"A": "1"}`, want: false},
+		{data: `{"A": "1", "sops": {}}`, want: true},
+		{data: `A=1`, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsEncrypted([]byte(tt.data)); got != tt.want {
+			t.Errorf("IsEncrypted(%s) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+// useTestdataKey makes the age key of the fixtures the identity of the test.
+func useTestdataKey(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv(AgeKeyEnv, "")
+	t.Setenv(AgeKeyFileEnv, "testdata/keys.txt")
+
+	return "age198w72qvhdmk66f9jf7swvp954jch5xww52ft88gwh2x97wqr0c3qegzpsk"
+}
+
+func readPlainFixture(t *testing.T) map[string]string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/plain.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+// TestDecrypt_sopsFixture decrypts a file encrypted by sops itself, run make
+// sops-fixtures to create it.
+func TestDecrypt_sopsFixture(t *testing.T) {
+	useTestdataKey(t)
+
+	data, err := os.ReadFile("testdata/sops.json")
+	if os.IsNotExist(err) {
+		t.Skip("testdata/sops.json doesn't exist, run make sops-fixtures with sops installed")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decrypt(data, nil)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+
+	if want := readPlainFixture(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decrypt() = %#v, want %#v", got, want)
+	}
+}
+
+// TestEncrypt_sopsDecrypt checks that sops decrypts files encrypted by ize.
+func TestEncrypt_sopsDecrypt(t *testing.T) {
+	path, err := exec.LookPath("sops")
+	if err != nil {
+		t.Skip("sops is not installed")
+	}
+
+	recipient := useTestdataKey(t)
+	want := readPlainFixture(t)
+
+	data, err := Encrypt(want, Keys{Age: []string{recipient}}, nil)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(path, "--decrypt", file)
+	cmd.Env = append(os.Environ(), "SOPS_AGE_KEY_FILE=testdata/keys.txt")
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sops --decrypt error = %v", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("sops --decrypt output %s: %v", out, err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sops --decrypt = %#v, want %#v", got, want)
+	}
+}
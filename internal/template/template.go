@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 )
 
 const (
@@ -40,6 +41,18 @@ func GenerateVarsTf(opts VarsOpts, path string) error {
 		rootBody.SetAttributeValue("root_domain_name", cty.StringVal(opts.ROOT_DOMAIN_NAME))
 	}
 
+	// user variables override the generated ones in place, the new ones are
+	// appended in alphabetical order
+	names := make([]string, 0, len(opts.VARS))
+	for name := range opts.VARS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rootBody.SetAttributeValue(name, opts.VARS[name])
+	}
+
 	file, err := os.Create(fmt.Sprintf("%s/%s", path, vars))
 	if err != nil {
 		return err
@@ -183,19 +196,16 @@ func GenerateBackendTf(opts BackendOpts, path string) error {
 
 		// Terraform block
 		terraformBlock := f.Body().AppendNewBlock("terraform", []string{})
-		// backend s3 block
-		backendBlock := terraformBlock.Body().AppendNewBlock("backend", []string{"s3"})
-		backendBlock.Body().SetAttributeValue("bucket", cty.StringVal(opts.TERRAFORM_STATE_BUCKET_NAME))
-		backendBlock.Body().SetAttributeValue("key", cty.StringVal(opts.TERRAFORM_STATE_KEY))
-		backendBlock.Body().SetAttributeValue("region", cty.StringVal(opts.TERRAFORM_STATE_REGION))
-		backendBlock.Body().SetAttributeValue("profile", cty.StringVal(opts.TERRAFORM_STATE_PROFILE))
-		backendBlock.Body().SetAttributeValue("dynamodb_table", cty.StringVal(opts.TERRAFORM_STATE_DYNAMODB_TABLE))
-		backendBlock.Body().SetAttributeValue("endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
-		backendBlock.Body().SetAttributeValue("sts_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
-		backendBlock.Body().SetAttributeValue("iam_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
-		backendBlock.Body().SetAttributeValue("dynamodb_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
-		backendBlock.Body().SetAttributeValue("force_path_style", cty.BoolVal(true))
-		backendBlock.Body().SetAttributeValue("shared_credentials_file", cty.StringVal("./localstack-user-credentials.config"))
+		// backend block, s3 is pointed to the localstack endpoint
+		backendBlock := appendBackendBlock(terraformBlock.Body(), opts)
+		if isS3Backend(opts) {
+			backendBlock.Body().SetAttributeValue("endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
+			backendBlock.Body().SetAttributeValue("sts_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
+			backendBlock.Body().SetAttributeValue("iam_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
+			backendBlock.Body().SetAttributeValue("dynamodb_endpoint", cty.StringVal(opts.LOCALSTACK_ENDPOINT))
+			backendBlock.Body().SetAttributeValue("force_path_style", cty.BoolVal(true))
+			backendBlock.Body().SetAttributeValue("shared_credentials_file", cty.StringVal("./localstack-user-credentials.config"))
+		}
 
 		defaultTagsBlock := providerBlock.Body().AppendNewBlock("default_tags", nil)
 		defaultTagsBlock.Body().SetAttributeValue("tags", cty.ObjectVal(map[string]cty.Value{
@@ -227,13 +237,7 @@ func GenerateBackendTf(opts BackendOpts, path string) error {
 
 		// Terraform block
 		terraformBlock := f.Body().AppendNewBlock("terraform", []string{})
-		// backend s3 block
-		backendBlock := terraformBlock.Body().AppendNewBlock("backend", []string{"s3"})
-		backendBlock.Body().SetAttributeValue("bucket", cty.StringVal(opts.TERRAFORM_STATE_BUCKET_NAME))
-		backendBlock.Body().SetAttributeValue("key", cty.StringVal(opts.TERRAFORM_STATE_KEY))
-		backendBlock.Body().SetAttributeValue("region", cty.StringVal(opts.TERRAFORM_STATE_REGION))
-		backendBlock.Body().SetAttributeValue("profile", cty.StringVal(opts.TERRAFORM_STATE_PROFILE))
-		backendBlock.Body().SetAttributeValue("dynamodb_table", cty.StringVal(opts.TERRAFORM_STATE_DYNAMODB_TABLE))
+		appendBackendBlock(terraformBlock.Body(), opts)
 	}
 
 	_, err := os.Stat(path)
@@ -278,6 +282,59 @@ func GenerateBackendTf(opts BackendOpts, path string) error {
 	return nil
 }
 
+func isS3Backend(opts BackendOpts) bool {
+	return len(opts.BACKEND_TYPE) == 0 || opts.BACKEND_TYPE == "s3"
+}
+
+// appendBackendBlock appends the backend block of the backend type to the
+// terraform block. Optional attributes are set only if they aren't empty.
+func appendBackendBlock(body *hclwrite.Body, opts BackendOpts) *hclwrite.Block {
+	backendType := opts.BACKEND_TYPE
+	if isS3Backend(opts) {
+		backendType = "s3"
+	}
+
+	backendBlock := body.AppendNewBlock("backend", []string{backendType})
+	b := backendBlock.Body()
+
+	setString := func(name, value string) {
+		if len(value) != 0 {
+			b.SetAttributeValue(name, cty.StringVal(value))
+		}
+	}
+	setBool := func(name string, value bool) {
+		if value {
+			b.SetAttributeValue(name, cty.True)
+		}
+	}
+
+	switch backendType {
+	case "s3":
+		b.SetAttributeValue("bucket", cty.StringVal(opts.TERRAFORM_STATE_BUCKET_NAME))
+		b.SetAttributeValue("key", cty.StringVal(opts.TERRAFORM_STATE_KEY))
+		b.SetAttributeValue("region", cty.StringVal(opts.TERRAFORM_STATE_REGION))
+		b.SetAttributeValue("profile", cty.StringVal(opts.TERRAFORM_STATE_PROFILE))
+		setString("dynamodb_table", opts.TERRAFORM_STATE_DYNAMODB_TABLE)
+		setBool("use_lockfile", opts.TERRAFORM_STATE_USE_LOCKFILE)
+		setString("workspace_key_prefix", opts.TERRAFORM_STATE_WORKSPACE_KEY_PREFIX)
+	case "local":
+		setString("path", opts.LOCAL_PATH)
+	case "http":
+		setString("address", opts.HTTP_ADDRESS)
+		setString("lock_address", opts.HTTP_LOCK_ADDRESS)
+		setString("unlock_address", opts.HTTP_UNLOCK_ADDRESS)
+		setString("lock_method", opts.HTTP_LOCK_METHOD)
+		setString("unlock_method", opts.HTTP_UNLOCK_METHOD)
+		setString("username", opts.HTTP_USERNAME)
+		setBool("skip_cert_verification", opts.HTTP_SKIP_CERT_VERIFICATION)
+	case "pg":
+		setString("conn_str", opts.PG_CONN_STR)
+		setString("schema_name", opts.PG_SCHEMA_NAME)
+	}
+
+	return backendBlock
+}
+
 type VarsOpts struct {
 	ENV               string
 	AWS_PROFILE       string
@@ -288,6 +345,9 @@ type VarsOpts struct {
 	SSH_PUBLIC_KEY    string
 	DOCKER_REGISTRY   string
 	NAMESPACE         string
+
+	// VARS are variables set in ize.toml, they override the variables above
+	VARS map[string]cty.Value
 }
 
 type BackendOpts struct {
@@ -300,6 +360,25 @@ type BackendOpts struct {
 	TERRAFORM_STATE_PROFILE        string
 	TERRAFORM_STATE_DYNAMODB_TABLE string
 	TERRAFORM_AWS_PROVIDER_VERSION string
+	TERRAFORM_STATE_USE_LOCKFILE   bool
+
+	TERRAFORM_STATE_WORKSPACE_KEY_PREFIX string
+
+	// BACKEND_TYPE is one of s3 (default), local, http and pg
+	BACKEND_TYPE string
+
+	LOCAL_PATH string
+
+	HTTP_ADDRESS                string
+	HTTP_LOCK_ADDRESS           string
+	HTTP_UNLOCK_ADDRESS         string
+	HTTP_LOCK_METHOD            string
+	HTTP_UNLOCK_METHOD          string
+	HTTP_USERNAME               string
+	HTTP_SKIP_CERT_VERIFICATION bool
+
+	PG_CONN_STR    string
+	PG_SCHEMA_NAME string
 }
 
 type ConfigOpts struct {
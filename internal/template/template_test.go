@@ -0,0 +1,217 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestGenerateBackendTf(t *testing.T) {
+	provider := `provider "aws" {
+  profile = var.aws_profile
+  region  = var.aws_region
+  default_tags {
+    tags = {
+      env       = "test"
+      namespace = "testnut"
+      terraform = "true"
+    }
+  }
+}
+
+`
+
+	tests := []struct {
+		name string
+		opts BackendOpts
+		want string
+	}{
+		{
+			name: "s3",
+			opts: BackendOpts{
+				TERRAFORM_STATE_BUCKET_NAME:    "testnut-tf-state",
+				TERRAFORM_STATE_KEY:            "test/terraform.tfstate",
+				TERRAFORM_STATE_REGION:         "us-east-1",
+				TERRAFORM_STATE_PROFILE:        "test",
+				TERRAFORM_STATE_DYNAMODB_TABLE: "tf-state-lock",
+			},
+			want: provider + `terraform {
+  backend "s3" {
+    bucket         = "testnut-tf-state"
+    key            = "test/terraform.tfstate"
+    region         = "us-east-1"
+    profile        = "test"
+    dynamodb_table = "tf-state-lock"
+  }
+}
+`,
+		},
+		{
+			name: "s3 with lock file",
+			opts: BackendOpts{
+				BACKEND_TYPE:                 "s3",
+				TERRAFORM_STATE_BUCKET_NAME:  "testnut-tf-state",
+				TERRAFORM_STATE_KEY:          "test/vpc.tfstate",
+				TERRAFORM_STATE_REGION:       "us-east-1",
+				TERRAFORM_STATE_PROFILE:      "test",
+				TERRAFORM_STATE_USE_LOCKFILE: true,
+			},
+			want: provider + `terraform {
+  backend "s3" {
+    bucket       = "testnut-tf-state"
+    key          = "test/vpc.tfstate"
+    region       = "us-east-1"
+    profile      = "test"
+    use_lockfile = true
+  }
+}
+`,
+		},
+		{
+			name: "s3 with workspaces",
+			opts: BackendOpts{
+				TERRAFORM_STATE_BUCKET_NAME:          "testnut-tf-state",
+				TERRAFORM_STATE_KEY:                  "terraform.tfstate",
+				TERRAFORM_STATE_REGION:               "us-east-1",
+				TERRAFORM_STATE_PROFILE:              "test",
+				TERRAFORM_STATE_DYNAMODB_TABLE:       "tf-state-lock",
+				TERRAFORM_STATE_WORKSPACE_KEY_PREFIX: "vpc",
+			},
+			want: provider + `terraform {
+  backend "s3" {
+    bucket               = "testnut-tf-state"
+    key                  = "terraform.tfstate"
+    region               = "us-east-1"
+    profile              = "test"
+    dynamodb_table       = "tf-state-lock"
+    workspace_key_prefix = "vpc"
+  }
+}
+`,
+		},
+		{
+			name: "local",
+			opts: BackendOpts{BACKEND_TYPE: "local", LOCAL_PATH: "state/terraform.tfstate"},
+			want: provider + `terraform {
+  backend "local" {
+    path = "state/terraform.tfstate"
+  }
+}
+`,
+		},
+		{
+			name: "local with localstack",
+			opts: BackendOpts{BACKEND_TYPE: "local", LOCALSTACK_ENDPOINT: "http://localhost:4566"},
+			want: `provider "aws" {
+  shared_credentials_files = ["./localstack-user-credentials.config"]
+  default_tags {
+    tags = {
+      env       = "test"
+      namespace = "testnut"
+      terraform = "true"
+    }
+  }
+}
+
+terraform {
+  backend "local" {
+  }
+}
+`,
+		},
+		{
+			name: "http",
+			opts: BackendOpts{
+				BACKEND_TYPE:                "http",
+				HTTP_ADDRESS:                "https://state.example.com/test/infra",
+				HTTP_LOCK_ADDRESS:           "https://state.example.com/test/infra/lock",
+				HTTP_UNLOCK_ADDRESS:         "https://state.example.com/test/infra/lock",
+				HTTP_USERNAME:               "ize",
+				HTTP_SKIP_CERT_VERIFICATION: true,
+			},
+			want: provider + `terraform {
+  backend "http" {
+    address                = "https://state.example.com/test/infra"
+    lock_address           = "https://state.example.com/test/infra/lock"
+    unlock_address         = "https://state.example.com/test/infra/lock"
+    username               = "ize"
+    skip_cert_verification = true
+  }
+}
+`,
+		},
+		{
+			name: "pg",
+			opts: BackendOpts{BACKEND_TYPE: "pg", PG_SCHEMA_NAME: "test_infra"},
+			want: provider + `terraform {
+  backend "pg" {
+    schema_name = "test_infra"
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.ENV = "test"
+			tt.opts.NAMESPACE = "testnut"
+
+			path := filepath.Join(t.TempDir(), "backend.tf")
+
+			if err := GenerateBackendTf(tt.opts, path); err != nil {
+				t.Fatalf("GenerateBackendTf() error = %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("GenerateBackendTf() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateVarsTf(t *testing.T) {
+	dir := t.TempDir()
+
+	err := GenerateVarsTf(VarsOpts{
+		ENV:               "test",
+		AWS_PROFILE:       "test",
+		AWS_REGION:        "us-east-1",
+		EC2_KEY_PAIR_NAME: "test-testnut",
+		SSH_PUBLIC_KEY:    "ssh-rsa AAA",
+		NAMESPACE:         "testnut",
+		VARS: map[string]cty.Value{
+			"instance_count": cty.NumberIntVal(2),
+			"aws_region":     cty.StringVal("eu-west-1"),
+			"azs":            cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		},
+	}, dir)
+	if err != nil {
+		t.Fatalf("GenerateVarsTf() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `env               = "test"
+aws_profile       = "test"
+aws_region        = "eu-west-1"
+ec2_key_pair_name = "test-testnut"
+ssh_public_key    = "ssh-rsa AAA"
+namespace         = "testnut"
+azs               = ["a", "b"]
+instance_count    = 2
+`
+
+	if string(got) != want {
+		t.Errorf("GenerateVarsTf() = \n%s\nwant\n%s", got, want)
+	}
+}
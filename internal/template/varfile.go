@@ -0,0 +1,47 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ReadVarFile reads the variables of a .tfvars or .tfvars.json file. Like in
+// terraform, the values must be literals.
+func ReadVarFile(path string) (map[string]cty.Value, error) {
+	parser := hclparse.NewParser()
+
+	var (
+		f     *hcl.File
+		diags hcl.Diagnostics
+	)
+
+	if strings.HasSuffix(path, ".json") {
+		f, diags = parser.ParseJSONFile(path)
+	} else {
+		f, diags = parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("can't parse %s: %s", path, diags.Error())
+	}
+
+	attrs, diags := f.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("can't read variables of %s: %s", path, diags.Error())
+	}
+
+	vars := map[string]cty.Value{}
+	for name, attr := range attrs {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("can't read variable %s of %s: %s", name, path, diags.Error())
+		}
+
+		vars[name] = v
+	}
+
+	return vars, nil
+}
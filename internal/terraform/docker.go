@@ -2,6 +2,8 @@ package terraform
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"github.com/hazelops/ize/internal/config"
 	"io"
@@ -25,22 +27,44 @@ import (
 
 const (
 	defaultName = "ize-terraform"
+
+	envLabel   = "sh.ize.env"
+	stackLabel = "sh.ize.stack"
 )
 
-func cleanupOldContainers(cli *client.Client) error {
+// containerName returns a name that is unique per run, so stacks can run in
+// parallel.
+func containerName(env string, state string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s", defaultName, env, state, hex.EncodeToString(b)), nil
+}
+
+// cleanupOldContainers removes stopped containers left by earlier runs of the
+// stack. Containers of other stacks and running containers are kept.
+func cleanupOldContainers(cli *client.Client, env string, state string) error {
 	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
 		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", fmt.Sprintf("%s=%s", envLabel, env)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", stackLabel, state)),
+		),
 	})
 	if err != nil {
 		return err
 	}
 
 	for _, c := range containers {
-		if strings.Contains(c.Names[0], defaultName) {
-			err = cli.ContainerRemove(context.Background(), c.ID, types.ContainerRemoveOptions{})
-			if err != nil {
-				return err
-			}
+		if c.State == "running" {
+			continue
+		}
+
+		err = cli.ContainerRemove(context.Background(), c.ID, types.ContainerRemoveOptions{})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -48,6 +72,7 @@ func cleanupOldContainers(cli *client.Client) error {
 }
 
 type docker struct {
+	engine  string
 	version string
 	command []string
 	env     []string
@@ -57,8 +82,12 @@ type docker struct {
 }
 
 func NewDockerTerraform(state string, command []string, env []string, out io.Writer, project *config.Project) *docker {
-	project.Terraform[state].Version = project.TerraformVersion
+	if len(project.Terraform[state].Version) == 0 {
+		project.Terraform[state].Version = DefaultVersion(project, state)
+	}
+
 	return &docker{
+		engine:  stackEngine(project, state),
 		state:   state,
 		version: project.Terraform[state].Version,
 		command: command,
@@ -95,12 +124,12 @@ func (d *docker) RunUI(ui terminal.UI) error {
 	s.Done()
 	s = sg.Add("Cleanuping old containers...")
 
-	err = cleanupOldContainers(cli)
+	err = cleanupOldContainers(cli, d.project.Env, d.state)
 	if err != nil {
 		return err
 	}
 
-	imageName := "hashicorp/terraform"
+	imageName := engineImage(d.engine)
 	imageTag := d.version
 
 	imageRef, err := reference.ParseNormalizedNamed(fmt.Sprintf("%s:%s", imageName, imageTag))
@@ -129,7 +158,7 @@ func (d *docker) RunUI(ui terminal.UI) error {
 	}
 
 	if len(imageList) == 0 {
-		s.Update("pulling terraform image %v:%v...", imageName, imageTag)
+		s.Update("pulling %s image %v:%v...", d.engine, imageName, imageTag)
 		out, err := cli.ImagePull(context.Background(), reference.FamiliarString(imageRef), types.ImagePullOptions{})
 		if err != nil {
 			return err
@@ -150,10 +179,7 @@ func (d *docker) RunUI(ui terminal.UI) error {
 	}
 
 	logrus.Infof("image name: %s, image tag: %s", imageName, imageTag)
-	stateDir := filepath.Join(d.project.EnvDir, d.state)
-	if d.state == "infra" {
-		stateDir = d.project.EnvDir
-	}
+	stateDir := d.project.StackDir(d.state)
 
 	contConfig := &container.Config{
 		User:         fmt.Sprintf("%v:%v", os.Getuid(), os.Getgid()),
@@ -165,31 +191,24 @@ func (d *docker) RunUI(ui terminal.UI) error {
 		AttachStderr: true,
 		OpenStdin:    true,
 		WorkingDir:   stateDir,
-		Env:          d.env,
+		Env:          append(d.env, fmt.Sprintf("%s=%s", PluginCacheEnv, PluginCacheDir())),
+		Labels: map[string]string{
+			envLabel:   d.project.Env,
+			stackLabel: d.state,
+		},
 	}
 
 	contHostConfig := &container.HostConfig{
 		AutoRemove: true,
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v", d.project.EnvDir),
-				Target: fmt.Sprintf("%v", d.project.EnvDir),
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v", d.project.InfraDir),
-				Target: fmt.Sprintf("%v", d.project.InfraDir),
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v/.aws", d.project.Home),
-				Target: "/.aws",
-			},
-		},
+		Mounts:     d.mounts(),
 	}
 
-	s.Update("[%s][%s] running terraform image %v:%v...", d.project.Env, d.state, imageName, imageTag)
+	s.Update("[%s][%s] running %s image %v:%v...", d.project.Env, d.state, d.engine, imageName, imageTag)
+
+	name, err := containerName(d.project.Env, d.state)
+	if err != nil {
+		return err
+	}
 
 	cont, err := cli.ContainerCreate(
 		context.Background(),
@@ -197,13 +216,16 @@ func (d *docker) RunUI(ui terminal.UI) error {
 		contHostConfig,
 		nil,
 		nil,
-		defaultName,
+		name,
 	)
 
 	if err != nil {
 		return err
 	}
 
+	// the lock is held until the container is removed
+	defer lockPluginCache(d.command)()
+
 	if err := cli.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{}); err != nil {
 		return err
 	}
@@ -234,7 +256,7 @@ func (d *docker) RunUI(ui terminal.UI) error {
 	select {
 	case status := <-wait:
 		if status.StatusCode != 0 {
-			return fmt.Errorf("container exit status code %d\n", status.StatusCode)
+			return &ExitError{Code: int(status.StatusCode)}
 		}
 		s.Done()
 		return nil
@@ -249,12 +271,12 @@ func (d *docker) Run() error {
 		return err
 	}
 
-	err = cleanupOldContainers(cli)
+	err = cleanupOldContainers(cli, d.project.Env, d.state)
 	if err != nil {
 		return err
 	}
 
-	imageName := "hashicorp/terraform"
+	imageName := engineImage(d.engine)
 	imageTag := d.version
 
 	imageRef, err := reference.ParseNormalizedNamed(fmt.Sprintf("%s:%s", imageName, imageTag))
@@ -300,29 +322,22 @@ func (d *docker) Run() error {
 		AttachStdout: true,
 		AttachStderr: true,
 		OpenStdin:    true,
-		WorkingDir:   fmt.Sprintf("%v", d.project.EnvDir),
-		Env:          d.env,
+		WorkingDir:   d.project.StackDir(d.state),
+		Env:          append(d.env, fmt.Sprintf("%s=%s", PluginCacheEnv, PluginCacheDir())),
+		Labels: map[string]string{
+			envLabel:   d.project.Env,
+			stackLabel: d.state,
+		},
 	}
 
 	contHostConfig := &container.HostConfig{
 		AutoRemove: true,
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v", d.project.EnvDir),
-				Target: fmt.Sprintf("%v", d.project.EnvDir),
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v", d.project.InfraDir),
-				Target: fmt.Sprintf("%v", d.project.InfraDir),
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: fmt.Sprintf("%v/.aws", d.project.Home),
-				Target: "/.aws",
-			},
-		},
+		Mounts:     d.mounts(),
+	}
+
+	name, err := containerName(d.project.Env, d.state)
+	if err != nil {
+		return err
 	}
 
 	cont, err := cli.ContainerCreate(
@@ -331,13 +346,16 @@ func (d *docker) Run() error {
 		contHostConfig,
 		nil,
 		nil,
-		defaultName,
+		name,
 	)
 
 	if err != nil {
 		return err
 	}
 
+	// the lock is held until the container is removed
+	defer lockPluginCache(d.command)()
+
 	if err := cli.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{}); err != nil {
 		return err
 	}
@@ -371,10 +389,61 @@ func (d *docker) Run() error {
 	select {
 	case status := <-wait:
 		if status.StatusCode != 0 {
-			return fmt.Errorf("container exit status code %d\n", status.StatusCode)
+			return &ExitError{Code: int(status.StatusCode)}
 		}
 		return nil
 	case err := <-errC:
 		return err
 	}
 }
+
+// mounts returns the env dir, the ize dir, the AWS config dir and the plugin
+// cache. The stack dir is mounted too when it's outside of the env and ize
+// dirs.
+func (d *docker) mounts() []mount.Mount {
+	mounts := []mount.Mount{
+		{
+			Type:   mount.TypeBind,
+			Source: fmt.Sprintf("%v", d.project.EnvDir),
+			Target: fmt.Sprintf("%v", d.project.EnvDir),
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: fmt.Sprintf("%v", d.project.InfraDir),
+			Target: fmt.Sprintf("%v", d.project.InfraDir),
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: fmt.Sprintf("%v/.aws", d.project.Home),
+			Target: "/.aws",
+		},
+	}
+
+	// the plugin cache is shared with the native runner
+	cacheDir := PluginCacheDir()
+	mounts = append(mounts, mount.Mount{
+		Type:   mount.TypeBind,
+		Source: cacheDir,
+		Target: cacheDir,
+	})
+
+	stackDir := d.project.StackDir(d.state)
+	if !isSubdir(d.project.EnvDir, stackDir) && !isSubdir(d.project.InfraDir, stackDir) {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: stackDir,
+			Target: stackDir,
+		})
+	}
+
+	return mounts
+}
+
+func isSubdir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
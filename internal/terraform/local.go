@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/hazelops/ize/internal/config"
@@ -31,6 +32,7 @@ const (
 )
 
 type local struct {
+	engine  string
 	version string
 	command []string
 	env     []string
@@ -42,10 +44,11 @@ type local struct {
 
 func NewLocalTerraform(state string, command []string, env []string, out io.Writer, project *config.Project) *local {
 	if len(project.Terraform[state].Version) == 0 {
-		project.Terraform[state].Version = project.TerraformVersion
+		project.Terraform[state].Version = DefaultVersion(project, state)
 	}
 
 	return &local{
+		engine:  stackEngine(project, state),
 		state:   state,
 		version: project.Terraform[state].Version,
 		command: command,
@@ -56,19 +59,21 @@ func NewLocalTerraform(state string, command []string, env []string, out io.Writ
 }
 
 func (l *local) Run() error {
-	if len(l.project.EnvDir) == 0 {
-		l.project.EnvDir = "."
+	// the project is shared by stacks that run in parallel, so it's not changed
+	envDir := l.project.EnvDir
+	if len(envDir) == 0 {
+		envDir = "."
 	}
 
-	stateDir := filepath.Join(l.project.EnvDir, l.state)
-	if l.state == "infra" {
-		stateDir = l.project.EnvDir
-	}
+	stateDir := l.project.StackDir(l.state)
 
 	cmd := exec.Command(l.tfpath, l.command...)
 	cmd.Dir = stateDir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", PluginCacheEnv, PluginCacheDir()))
+
+	defer lockPluginCache(l.command)()
 
-	err := term.New(term.WithDir(l.project.EnvDir), term.WithStdin(os.Stdin)).InteractiveRun(cmd)
+	err := term.New(term.WithDir(envDir), term.WithStdin(os.Stdin)).InteractiveRun(cmd)
 	if err != nil {
 		return err
 	}
@@ -80,9 +85,17 @@ func (l *local) Prepare() error {
 	var (
 		mirror = defaultMirror
 		path   = ""
+		err    error
 	)
 
-	path, err := installVersion(l.version, &mirror)
+	switch l.engine {
+	case EngineTerraform:
+		path, err = installVersion(l.version, &mirror)
+	case EngineOpenTofu:
+		path, err = InstallTofu(l.version, defaultTofuMirror)
+	default:
+		return fmt.Errorf("%s engine isn't supported (terraform or opentofu)", l.engine)
+	}
 	if err != nil {
 		return err
 	}
@@ -102,6 +115,8 @@ func (l *local) SetOut(out io.Writer) {
 
 func printOutput(r io.Reader, w io.Writer) {
 	scanner := bufio.NewScanner(r)
+	// the JSON output of terraform show is a single line
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
 	for scanner.Scan() {
 		w.Write([]byte(scanner.Text() + "\n"))
 	}
@@ -124,15 +139,19 @@ func runCommand(cmd *exec.Cmd, out io.Writer) (stdout, stderr string, err error)
 		return
 	}
 
-	go printOutput(outReader, out)
-	go printOutput(errReader, out)
+	// the output must be read before Wait closes the pipes
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { printOutput(outReader, out); wg.Done() }()
+	go func() { printOutput(errReader, out); wg.Done() }()
+	wg.Wait()
 
 	err = cmd.Wait()
 
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if s, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				err = fmt.Errorf("exit status: %d", s.ExitStatus())
+				err = &ExitError{Code: s.ExitStatus()}
 			}
 		}
 	}
@@ -143,7 +162,7 @@ func (l *local) RunUI(ui terminal.UI) error {
 	sg := ui.StepGroup()
 	defer sg.Wait()
 
-	s := sg.Add("[%s][%s] Running terraform v%s...", l.project.Env, l.state, l.version)
+	s := sg.Add("[%s][%s] Running %s v%s...", l.project.Env, l.state, l.engine, l.version)
 	defer func() { s.Abort(); time.Sleep(time.Millisecond * 100) }()
 
 	stdout := s.TermOutput()
@@ -151,18 +170,15 @@ func (l *local) RunUI(ui terminal.UI) error {
 		stdout = l.output
 	}
 
-	if len(l.project.EnvDir) == 0 {
-		l.project.EnvDir = "."
-	}
-
-	stateDir := filepath.Join(l.project.EnvDir, l.state)
-	if l.state == "infra" {
-		stateDir = l.project.EnvDir
-	}
+	stateDir := l.project.StackDir(l.state)
 
 	cmd := exec.Command(l.tfpath, l.command...)
 	cmd.Dir = stateDir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", PluginCacheEnv, PluginCacheDir()))
+
+	unlock := lockPluginCache(l.command)
 	_, _, err := runCommand(cmd, stdout)
+	unlock()
 
 	if err != nil {
 		return err
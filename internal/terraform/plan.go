@@ -0,0 +1,273 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resource change actions of the terraform JSON plan representation.
+const (
+	ActionNoop    = "no-op"
+	ActionCreate  = "create"
+	ActionRead    = "read"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionReplace = "replace"
+)
+
+// Plan is the part of the `terraform show -json <plan>` output ize needs to
+// review a plan.
+type Plan struct {
+	FormatVersion   string                  `json:"format_version"`
+	ResourceChanges []ResourceChange        `json:"resource_changes"`
+	ResourceDrift   []ResourceChange        `json:"resource_drift"`
+	OutputChanges   map[string]OutputChange `json:"output_changes"`
+}
+
+type ResourceChange struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Change  Change `json:"change"`
+}
+
+type OutputChange struct {
+	Actions []string `json:"actions"`
+}
+
+type Change struct {
+	Actions []string `json:"actions"`
+}
+
+// Action returns the action of the change, a pair of delete and create
+// actions is reported as replace.
+func (c Change) Action() string {
+	switch {
+	case len(c.Actions) == 2:
+		return ActionReplace
+	case len(c.Actions) == 1:
+		return c.Actions[0]
+	default:
+		return ActionNoop
+	}
+}
+
+// ParsePlan parses the JSON representation of a plan. Anything printed
+// before the JSON document (e.g. by the docker runner) is skipped.
+func ParsePlan(data []byte) (*Plan, error) {
+	i := bytes.IndexByte(data, '{')
+	if i < 0 {
+		return nil, fmt.Errorf("can't parse plan: no JSON found in the terraform show output")
+	}
+
+	var p Plan
+	if err := json.NewDecoder(bytes.NewReader(data[i:])).Decode(&p); err != nil {
+		return nil, fmt.Errorf("can't parse plan: %w", err)
+	}
+
+	return &p, nil
+}
+
+// DriftedResources returns the sorted addresses of the resources that were
+// changed outside of terraform.
+func (p *Plan) DriftedResources() []string {
+	var addresses []string
+	for _, rc := range p.ResourceDrift {
+		addresses = append(addresses, rc.Address)
+	}
+
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// PlanSummary is the list of resources a plan changes.
+type PlanSummary struct {
+	Add     int
+	Change  int
+	Destroy int
+	Outputs int
+
+	Resources []ResourceChange
+}
+
+// Summary counts the changes the same way terraform does: a replaced
+// resource is counted as both added and destroyed. Data sources that are
+// read and resources without changes are skipped.
+func (p *Plan) Summary() PlanSummary {
+	var s PlanSummary
+
+	for _, rc := range p.ResourceChanges {
+		switch rc.Change.Action() {
+		case ActionCreate:
+			s.Add++
+		case ActionUpdate:
+			s.Change++
+		case ActionDelete:
+			s.Destroy++
+		case ActionReplace:
+			s.Add++
+			s.Destroy++
+		default:
+			continue
+		}
+
+		s.Resources = append(s.Resources, rc)
+	}
+
+	for _, oc := range p.OutputChanges {
+		if len(oc.Actions) != 0 && oc.Actions[0] != ActionNoop {
+			s.Outputs++
+		}
+	}
+
+	sort.Slice(s.Resources, func(i, j int) bool {
+		return s.Resources[i].Address < s.Resources[j].Address
+	})
+
+	return s
+}
+
+// HasChanges reports whether applying the plan changes anything.
+func (s PlanSummary) HasChanges() bool {
+	return len(s.Resources) != 0 || s.Outputs != 0
+}
+
+// String returns the per resource list of changes followed by the totals.
+func (s PlanSummary) String() string {
+	var b strings.Builder
+
+	for _, rc := range s.Resources {
+		fmt.Fprintf(&b, "  %s %s (%s)\n", actionSymbol(rc.Change.Actions), rc.Address, rc.Change.Action())
+	}
+
+	if s.Outputs != 0 {
+		fmt.Fprintf(&b, "  ~ %d output(s)\n", s.Outputs)
+	}
+
+	fmt.Fprintf(&b, "Plan: %d to add, %d to change, %d to destroy.", s.Add, s.Change, s.Destroy)
+
+	return b.String()
+}
+
+// Markdown returns the totals followed by a table of the changed resources,
+// e.g. to be posted as a pull request comment.
+func (s PlanSummary) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "**Plan:** %d to add, %d to change, %d to destroy.\n", s.Add, s.Change, s.Destroy)
+
+	if len(s.Resources) != 0 {
+		b.WriteString("\n| Action | Resource |\n|--------|----------|\n")
+		for _, rc := range s.Resources {
+			fmt.Fprintf(&b, "| `%s` %s | `%s` |\n", actionSymbol(rc.Change.Actions), rc.Change.Action(), rc.Address)
+		}
+	}
+
+	if s.Outputs != 0 {
+		fmt.Fprintf(&b, "\n%d output(s) will change.\n", s.Outputs)
+	}
+
+	return b.String()
+}
+
+func actionSymbol(actions []string) string {
+	switch strings.Join(actions, ",") {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionDelete:
+		return "-"
+	case ActionDelete + "," + ActionCreate:
+		return "-/+"
+	case ActionCreate + "," + ActionDelete:
+		return "+/-"
+	default:
+		return " "
+	}
+}
+
+// Policy restricts the destructive changes of a plan. Replaced resources
+// are destroyed too, so they are treated the same way as deleted ones.
+type Policy struct {
+	// PreventDestroyTypes are the resource types that can't be destroyed.
+	PreventDestroyTypes []string
+	// MaxDestroys is the maximum number of destroyed resources, nil means
+	// there is no limit.
+	MaxDestroys *int
+	// ProtectedResources are the addresses of resources or modules that
+	// can't be destroyed.
+	ProtectedResources []string
+}
+
+// Violations returns the descriptions of the changes that violate the policy.
+func (s PlanSummary) Violations(p Policy) []string {
+	var violations []string
+
+	destroys := 0
+	for _, rc := range s.Resources {
+		action := rc.Change.Action()
+		if action != ActionDelete && action != ActionReplace {
+			continue
+		}
+		destroys++
+
+		for _, t := range p.PreventDestroyTypes {
+			if rc.Type == t {
+				violations = append(violations, fmt.Sprintf("%s would be %sd, destroying %s resources is prevented", rc.Address, action, t))
+			}
+		}
+
+		for _, a := range p.ProtectedResources {
+			if containsAddress(a, rc.Address) {
+				violations = append(violations, fmt.Sprintf("%s would be %sd, it's protected by %s", rc.Address, action, a))
+			}
+		}
+	}
+
+	if p.MaxDestroys != nil && destroys > *p.MaxDestroys {
+		violations = append(violations, fmt.Sprintf("%d resources would be destroyed, at most %d are allowed", destroys, *p.MaxDestroys))
+	}
+
+	return violations
+}
+
+// containsAddress reports whether the resource address is the protected
+// address or belongs to it, e.g. module.db protects module.db.aws_db_instance.main
+// and aws_instance.web protects aws_instance.web[0].
+func containsAddress(protected, address string) bool {
+	if address == protected {
+		return true
+	}
+
+	return strings.HasPrefix(address, protected+".") || strings.HasPrefix(address, protected+"[")
+}
+
+// StateVersion identifies a version of a state: the serial is incremented by
+// every change of the state with the same lineage.
+type StateVersion struct {
+	Serial  uint64 `json:"serial"`
+	Lineage string `json:"lineage"`
+}
+
+// ParseStateVersion parses the version of the `terraform state pull` output.
+// A stack without a state has the zero version.
+func ParseStateVersion(data []byte) (StateVersion, error) {
+	var v StateVersion
+
+	i := bytes.IndexByte(data, '{')
+	if i < 0 {
+		return v, nil
+	}
+
+	if err := json.NewDecoder(bytes.NewReader(data[i:])).Decode(&v); err != nil {
+		return v, fmt.Errorf("can't parse state: %w", err)
+	}
+
+	return v, nil
+}
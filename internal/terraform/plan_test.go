@@ -0,0 +1,220 @@
+package terraform
+
+import (
+	"testing"
+)
+
+const testPlan = `{
+  "format_version": "1.1",
+  "resource_changes": [
+    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs", "change": {"actions": ["create"]}},
+    {"address": "aws_db_instance.main", "mode": "managed", "type": "aws_db_instance", "name": "main", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_security_group.db", "mode": "managed", "type": "aws_security_group", "name": "db", "change": {"actions": ["update"]}},
+    {"address": "aws_iam_role.old", "mode": "managed", "type": "aws_iam_role", "name": "old", "change": {"actions": ["delete"]}},
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "change": {"actions": ["no-op"]}},
+    {"address": "data.aws_caller_identity.current", "mode": "data", "type": "aws_caller_identity", "name": "current", "change": {"actions": ["read"]}}
+  ],
+  "output_changes": {
+    "vpc_id": {"actions": ["no-op"]}
+  }
+}`
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantSummary string
+		wantChanges bool
+		wantErr     bool
+	}{
+		{
+			name: "changes",
+			data: testPlan,
+			wantSummary: "  -/+ aws_db_instance.main (replace)\n" +
+				"  - aws_iam_role.old (delete)\n" +
+				"  + aws_s3_bucket.logs (create)\n" +
+				"  ~ aws_security_group.db (update)\n" +
+				"Plan: 2 to add, 1 to change, 2 to destroy.",
+			wantChanges: true,
+		},
+		{
+			name:        "no changes",
+			data:        "\r\n" + `{"format_version":"1.1","resource_changes":[{"address":"aws_vpc.main","change":{"actions":["no-op"]}}]}` + "\r\n",
+			wantSummary: "Plan: 0 to add, 0 to change, 0 to destroy.",
+			wantChanges: false,
+		},
+		{
+			name:        "outputs only",
+			data:        `{"format_version":"1.1","output_changes":{"vpc_id":{"actions":["create"]}}}`,
+			wantSummary: "  ~ 1 output(s)\nPlan: 0 to add, 0 to change, 0 to destroy.",
+			wantChanges: true,
+		},
+		{
+			name:    "no json",
+			data:    "Error: Failed to read the given file as a state or plan file",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePlan([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			s := p.Summary()
+			if got := s.String(); got != tt.wantSummary {
+				t.Errorf("Summary() = %q, want %q", got, tt.wantSummary)
+			}
+			if got := s.HasChanges(); got != tt.wantChanges {
+				t.Errorf("HasChanges() = %v, want %v", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestPlanSummary_Violations(t *testing.T) {
+	zero, two := 0, 2
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{
+			name:   "no policy",
+			policy: Policy{},
+		},
+		{
+			name:   "prevented type",
+			policy: Policy{PreventDestroyTypes: []string{"aws_db_instance", "aws_s3_bucket"}},
+			want:   []string{"aws_db_instance.main would be replaced, destroying aws_db_instance resources is prevented"},
+		},
+		{
+			name:   "protected address",
+			policy: Policy{ProtectedResources: []string{"aws_iam_role.old", "aws_s3_bucket.logs", "aws_db"}},
+			want:   []string{"aws_iam_role.old would be deleted, it's protected by aws_iam_role.old"},
+		},
+		{
+			name:   "max destroys",
+			policy: Policy{MaxDestroys: &zero},
+			want:   []string{"2 resources would be destroyed, at most 0 are allowed"},
+		},
+		{
+			name:   "max destroys not exceeded",
+			policy: Policy{MaxDestroys: &two},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePlan([]byte(testPlan))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := p.Summary().Violations(tt.policy)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Violations() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Violations()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_containsAddress(t *testing.T) {
+	tests := []struct {
+		protected string
+		address   string
+		want      bool
+	}{
+		{protected: "aws_db_instance.main", address: "aws_db_instance.main", want: true},
+		{protected: "aws_db_instance.main", address: "aws_db_instance.main_replica", want: false},
+		{protected: "aws_instance.web", address: "aws_instance.web[0]", want: true},
+		{protected: "module.db", address: "module.db.aws_db_instance.main", want: true},
+		{protected: "module.db", address: `module.db["eu"].aws_db_instance.main`, want: true},
+		{protected: "module.db", address: "module.dbx.aws_db_instance.main", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := containsAddress(tt.protected, tt.address); got != tt.want {
+				t.Errorf("containsAddress(%q, %q) = %v, want %v", tt.protected, tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_DriftedResources(t *testing.T) {
+	p, err := ParsePlan([]byte(`{"format_version":"1.1","resource_drift":[
+		{"address":"aws_security_group.db","change":{"actions":["update"]}},
+		{"address":"aws_instance.bastion","change":{"actions":["delete"]}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := p.DriftedResources()
+	want := []string{"aws_instance.bastion", "aws_security_group.db"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("DriftedResources() = %q, want %q", got, want)
+	}
+}
+
+func TestPlanSummary_Markdown(t *testing.T) {
+	p, err := ParsePlan([]byte(testPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "**Plan:** 2 to add, 1 to change, 2 to destroy.\n" +
+		"\n| Action | Resource |\n|--------|----------|\n" +
+		"| `-/+` replace | `aws_db_instance.main` |\n" +
+		"| `-` delete | `aws_iam_role.old` |\n" +
+		"| `+` create | `aws_s3_bucket.logs` |\n" +
+		"| `~` update | `aws_security_group.db` |\n"
+
+	if got := p.Summary().Markdown(); got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
+
+func TestParseStateVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    StateVersion
+		wantErr bool
+	}{
+		{
+			name: "state",
+			data: `{"version": 4, "terraform_version": "1.5.7", "serial": 12, "lineage": "3c4d", "outputs": {}, "resources": []}`,
+			want: StateVersion{Serial: 12, Lineage: "3c4d"},
+		},
+		{
+			name: "no state",
+			data: "",
+		},
+		{
+			name:    "invalid state",
+			data:    `{"serial": "12"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStateVersion([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStateVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStateVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package terraform
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/sirupsen/logrus"
 )
 
 type Terraform interface {
@@ -13,3 +18,54 @@ type Terraform interface {
 	NewCmd(cmd []string)
 	SetOut(out io.Writer)
 }
+
+// ExitError is returned by the runners when terraform exits with a non-zero
+// exit code, e.g. 2 for a plan with changes when -detailed-exitcode is set.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status: %d", e.Code)
+}
+
+// PluginCacheEnv is the environment variable of the provider plugin cache.
+const PluginCacheEnv = "TF_PLUGIN_CACHE_DIR"
+
+// PluginCacheDir returns the provider plugin cache shared by all stacks:
+// TF_PLUGIN_CACHE_DIR if it's set, ~/.ize/plugin-cache otherwise. Terraform
+// ignores a cache dir that doesn't exist, so it's created.
+func PluginCacheDir() string {
+	if dir := os.Getenv(PluginCacheEnv); len(dir) != 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logrus.Debugf("can't create plugin cache %s: %s", dir, err)
+		}
+		return dir
+	}
+
+	return getInstallLocation(".ize/plugin-cache/")
+}
+
+// pluginCacheMu serializes commands that install providers, the plugin cache
+// is not safe for concurrent installs.
+var pluginCacheMu sync.Mutex
+
+// lockPluginCache locks the plugin cache if the command installs providers to
+// it and returns the function that unlocks it.
+func lockPluginCache(command []string) func() {
+	for _, arg := range command {
+		if strings.HasPrefix(arg, "-") {
+			// global options like -chdir come before the subcommand
+			continue
+		}
+
+		if arg == "init" || arg == "providers" {
+			pluginCacheMu.Lock()
+			return pluginCacheMu.Unlock
+		}
+
+		break
+	}
+
+	return func() {}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/hazelops/ize/pkg/terminal"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -230,3 +232,111 @@ func home() string {
 	h, _ := os.UserHomeDir()
 	return h
 }
+
+func Test_tofuDownloadURL(t *testing.T) {
+	got := tofuDownloadURL(defaultTofuMirror+"/", "1.8.2", "linux", "amd64")
+	want := "https://github.com/opentofu/opentofu/releases/download/v1.8.2/tofu_1.8.2_linux_amd64.zip"
+	if got != want {
+		t.Errorf("tofuDownloadURL() = %s, want %s", got, want)
+	}
+}
+
+func TestInstallTofu_invalidVersion(t *testing.T) {
+	for _, v := range []string{"latest", "1.5.7"} {
+		if _, err := InstallTofu(v, defaultTofuMirror); err == nil {
+			t.Errorf("InstallTofu(%s) error = nil, want error", v)
+		}
+	}
+}
+
+func TestPluginCacheDir(t *testing.T) {
+	t.Setenv(PluginCacheEnv, "/tmp/ize-plugin-cache")
+
+	if got := PluginCacheDir(); got != "/tmp/ize-plugin-cache" {
+		t.Errorf("PluginCacheDir() = %s, want %s", got, "/tmp/ize-plugin-cache")
+	}
+}
+
+func Test_containerName(t *testing.T) {
+	first, err := containerName("testnut", "infra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := containerName("testnut", "infra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Errorf("containerName() = %s twice, want unique names", first)
+	}
+
+	if !strings.HasPrefix(first, "ize-terraform-testnut-infra-") {
+		t.Errorf("containerName() = %s, want ize-terraform-testnut-infra-<id>", first)
+	}
+}
+
+func TestNewDockerTerraform_version(t *testing.T) {
+	project := &config.Project{
+		TerraformVersion: "1.1.3",
+		Terraform: map[string]*config.Terraform{
+			"infra": {},
+			"dns":   {Engine: EngineOpenTofu},
+			"vpc":   {Engine: EngineOpenTofu, Version: "1.7.0"},
+		},
+	}
+
+	want := map[string]string{
+		"infra": "1.1.3",
+		"dns":   DefaultTofuVersion,
+		"vpc":   "1.7.0",
+	}
+
+	for name, version := range want {
+		if got := NewDockerTerraform(name, nil, nil, nil, project).version; got != version {
+			t.Errorf("version of %s = %s, want %s", name, got, version)
+		}
+	}
+}
+
+func TestPluginCacheDir_create(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plugin-cache")
+	t.Setenv(PluginCacheEnv, dir)
+
+	if got := PluginCacheDir(); got != dir {
+		t.Errorf("PluginCacheDir() = %s, want %s", got, dir)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("PluginCacheDir() didn't create %s: %v", dir, err)
+	}
+}
+
+func Test_lockPluginCache(t *testing.T) {
+	tests := []struct {
+		command []string
+		locked  bool
+	}{
+		{command: []string{"init", "-input=true"}, locked: true},
+		{command: []string{"-chdir=infra", "init"}, locked: true},
+		{command: []string{"providers", "lock"}, locked: true},
+		{command: []string{"plan", "-out=tfplan"}, locked: false},
+		{command: []string{"output", "init"}, locked: false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.command, " "), func(t *testing.T) {
+			unlock := lockPluginCache(tt.command)
+
+			if locked := !pluginCacheMu.TryLock(); locked != tt.locked {
+				t.Errorf("lockPluginCache() locked = %v, want %v", locked, tt.locked)
+			}
+
+			if tt.locked {
+				unlock()
+			} else {
+				pluginCacheMu.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hazelops/ize/internal/config"
+	tfswitcher "github.com/psihachina/terraform-switcher/lib"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EngineTerraform = "terraform"
+	EngineOpenTofu  = "opentofu"
+
+	// DefaultTofuVersion is used by opentofu stacks without a version, the
+	// terraform_version of the project doesn't apply to them.
+	DefaultTofuVersion = "1.8.2"
+
+	tofuVersionPrefix = "tofu_"
+	defaultTofuMirror = "https://github.com/opentofu/opentofu/releases/download"
+	tofuImage         = "ghcr.io/opentofu/opentofu"
+	terraformImage    = "hashicorp/terraform"
+)
+
+// tofuMinVersion is the first OpenTofu release
+var tofuMinVersion = version.Must(version.NewVersion("1.6.0"))
+
+// stackEngine returns the engine of the stack, terraform by default.
+func stackEngine(project *config.Project, state string) string {
+	if e := project.Terraform[state].Engine; len(e) != 0 {
+		return e
+	}
+
+	return EngineTerraform
+}
+
+// DefaultVersion returns the version of the engine of a stack that doesn't
+// set its own.
+func DefaultVersion(project *config.Project, state string) string {
+	if stackEngine(project, state) == EngineOpenTofu {
+		return DefaultTofuVersion
+	}
+
+	return project.TerraformVersion
+}
+
+// engineImage returns the docker image of the engine.
+func engineImage(engine string) string {
+	if engine == EngineOpenTofu {
+		return tofuImage
+	}
+
+	return terraformImage
+}
+
+func tofuDownloadURL(mirrorURL string, tofuVersion string, goos string, goarch string) string {
+	return fmt.Sprintf("%s/v%s/%s%s_%s_%s.zip", strings.TrimSuffix(mirrorURL, "/"), tofuVersion, tofuVersionPrefix, tofuVersion, goos, goarch)
+}
+
+// InstallTofu downloads the OpenTofu version to ~/.ize/versions/tofu unless it
+// has been downloaded before and returns the path of the binary.
+func InstallTofu(tofuVersion string, mirrorURL string) (string, error) {
+	if !tfswitcher.ValidVersionFormat(tofuVersion) {
+		return "", fmt.Errorf("argument must be a valid opentofu version")
+	}
+
+	v, err := version.NewVersion(tofuVersion)
+	if err != nil {
+		return "", fmt.Errorf("argument must be a valid opentofu version: %w", err)
+	}
+
+	if v.LessThan(tofuMinVersion) {
+		return "", fmt.Errorf("opentofu %s doesn't exist, the first release is %s, set the version of the stack", tofuVersion, tofuMinVersion)
+	}
+
+	installLocation := getInstallLocation(".ize/versions/tofu/")
+	installFileVersionPath := tfswitcher.ConvertExecutableExt(filepath.Join(installLocation, tofuVersionPrefix+tofuVersion))
+
+	if tfswitcher.CheckFileExist(installFileVersionPath) {
+		return installFileVersionPath, nil
+	}
+
+	// the archive has a readme and a license besides the binary, so it's
+	// unpacked to a temporary directory
+	tmp, err := os.MkdirTemp(installLocation, "download")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	url := tofuDownloadURL(mirrorURL, tofuVersion, runtime.GOOS, runtime.GOARCH)
+
+	logrus.Debugf("downloading opentofu version %s to: %s", tofuVersion, installLocation)
+
+	zipFile, err := tfswitcher.DownloadFromURL(tmp, url)
+	if err != nil {
+		return "", fmt.Errorf("can't download opentofu %s: %w", tofuVersion, err)
+	}
+
+	_, err = tfswitcher.Unzip(zipFile, tmp)
+	if err != nil {
+		return "", fmt.Errorf("can't unzip opentofu %s: %w", tofuVersion, err)
+	}
+
+	err = os.Rename(tfswitcher.ConvertExecutableExt(filepath.Join(tmp, "tofu")), installFileVersionPath)
+	if err != nil {
+		return "", fmt.Errorf("can't install opentofu %s: %w", tofuVersion, err)
+	}
+
+	return installFileVersionPath, nil
+}
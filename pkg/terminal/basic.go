@@ -1,7 +1,6 @@
 package terminal
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -10,7 +9,6 @@ import (
 	"strings"
 	"text/tabwriter"
 
-	"github.com/bgentry/speakeasy"
 	"github.com/containerd/console"
 	"github.com/fatih/color"
 	"github.com/mattn/go-isatty"
@@ -63,20 +61,13 @@ func (ui *basicUI) Input(input *Input) (string, error) {
 	errCh := make(chan error, 1)
 	lineCh := make(chan string, 1)
 	go func() {
-		var line string
-		var err error
-		if input.Secret && isatty.IsTerminal(os.Stdin.Fd()) {
-			line, err = speakeasy.Ask("")
-		} else {
-			r := bufio.NewReader(os.Stdin)
-			line, err = r.ReadString('\n')
-		}
+		line, err := readInput(input)
 		if err != nil {
 			errCh <- err
 			return
 		}
 
-		lineCh <- strings.TrimRight(line, "\r\n")
+		lineCh <- line
 	}()
 
 	select {
@@ -7,15 +7,20 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
+	"github.com/mattn/go-isatty"
 	"github.com/mitchellh/go-glint"
 	"github.com/olekukonko/tablewriter"
 )
 
 type glintUI struct {
 	d *glint.Document
+
+	// mu serializes inputs, stacks that run in parallel can prompt at once
+	mu sync.Mutex
 }
 
 func GlintUI(ctx context.Context) UI {
@@ -32,16 +37,29 @@ func (ui *glintUI) Close() error {
 	return ui.d.Close()
 }
 
+// Input implements UI. Glint doesn't support input, so the document is
+// rendered and paused while the answer is read from stdin.
 func (ui *glintUI) Input(input *Input) (string, error) {
-	return "", ErrNonInteractive
+	if !ui.Interactive() {
+		return "", ErrNonInteractive
+	}
+
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	ui.d.RenderFrame()
+	ui.d.Pause()
+	defer ui.d.Resume()
+
+	msg, _, _ := Interpret(input.Prompt, WithStyle(input.Style))
+	fmt.Fprint(os.Stdout, strings.TrimRight(msg, "\r\n")+" ")
+
+	return readInput(input)
 }
 
 // Interactive implements UI
 func (ui *glintUI) Interactive() bool {
-	// TODO(mitchellh): We can make this interactive later but Glint itself
-	// doesn't support input yet. We can pause the document, do some input,
-	// then resume potentially.
-	return false
+	return isatty.IsTerminal(os.Stdin.Fd())
 }
 
 // Output implements UI
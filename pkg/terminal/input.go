@@ -1,5 +1,15 @@
 package terminal
 
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bgentry/speakeasy"
+	"github.com/mattn/go-isatty"
+)
+
 // Input is the configuration for an input.
 type Input struct {
 	// Prompt is a single-line prompt to give the user such as "Continue?"
@@ -13,3 +23,27 @@ type Input struct {
 	// True if this input is a secret. The input will be masked.
 	Secret bool
 }
+
+var (
+	// stdin is shared by all inputs, a reader per input could buffer the
+	// answers to the next ones.
+	stdin   = bufio.NewReader(os.Stdin)
+	stdinMu sync.Mutex
+)
+
+// readInput reads the answer to the input from stdin.
+func readInput(input *Input) (string, error) {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+
+	if input.Secret && isatty.IsTerminal(os.Stdin.Fd()) {
+		return speakeasy.Ask("")
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -11,6 +11,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -23,13 +24,24 @@ func NonInteractiveUI(ctx context.Context) UI {
 	return result
 }
 
+// Input implements UI. The answer is read from stdin when it is a terminal.
 func (ui *nonInteractiveUI) Input(input *Input) (string, error) {
-	return "", ErrNonInteractive
+	if !ui.Interactive() {
+		return "", ErrNonInteractive
+	}
+
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	msg, _, w := Interpret(input.Prompt, WithStyle(input.Style))
+	fmt.Fprint(w, strings.TrimRight(msg, "\r\n")+" ")
+
+	return readInput(input)
 }
 
 // Interactive implements UI
 func (ui *nonInteractiveUI) Interactive() bool {
-	return false
+	return isatty.IsTerminal(os.Stdin.Fd())
 }
 
 // Output implements UI
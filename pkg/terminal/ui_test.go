@@ -4,6 +4,7 @@
 package terminal
 
 import (
+	"bufio"
 	"bytes"
 	"strings"
 	"testing"
@@ -85,3 +86,25 @@ two
 
 	require.Equal(expected, buf.String())
 }
+
+func TestReadInput_shared(t *testing.T) {
+	require := require.New(t)
+
+	old := stdin
+	defer func() { stdin = old }()
+	stdin = bufio.NewReader(strings.NewReader("yes\r\nno\n"))
+
+	// parallel inputs get whole lines, nothing is lost to read-ahead
+	answers := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			answer, err := readInput(&Input{Prompt: "Continue?"})
+			if err != nil {
+				answer = err.Error()
+			}
+			answers <- answer
+		}()
+	}
+
+	require.ElementsMatch([]string{"yes", "no"}, []string{<-answers, <-answers})
+}